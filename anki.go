@@ -0,0 +1,544 @@
+package yomichan
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type ankiModel struct {
+	Name   string `json:"name"`
+	Fields []struct {
+		Name string `json:"name"`
+		Ord  int    `json:"ord"`
+	} `json:"flds"`
+}
+
+type ankiNoteType struct {
+	name   string
+	fields []string
+}
+
+type ankiNote struct {
+	sequence int
+	model    int64
+	fields   []string
+	tags     []string
+}
+
+type ankiCollection struct {
+	models map[int64]ankiNoteType
+	notes  []ankiNote
+	media  map[string][]byte
+}
+
+var (
+	ankiSoundExp    = regexp.MustCompile(`\[sound:[^\]]*\]`)
+	ankiFuriganaExp = regexp.MustCompile(` ?([^ \[\]]+)\[([^\]]+)\]`)
+)
+
+// The default mapping uses the first field as the expression and the
+// second as the glossary, which matches the basic note types.
+var ankiDefaultFields = map[string][]string{
+	"expression": {"1"},
+	"glossary":   {"2"},
+}
+
+// Copies the collection database from an .apkg archive to a temporary
+// file, since SQLite cannot read from memory. Newer archives contain a
+// zstd compressed collection.anki21b alongside a legacy placeholder,
+// which is not supported.
+func ankiExtractCollection(archive *zip.ReadCloser) (string, error) {
+	var collection *zip.File
+	for _, name := range []string{"collection.anki21", "collection.anki2"} {
+		for _, file := range archive.File {
+			if file.Name == name {
+				collection = file
+				break
+			}
+		}
+		if collection != nil {
+			break
+		}
+	}
+
+	if collection == nil {
+		return "", errors.New("no collection found in Anki package")
+	}
+
+	for _, file := range archive.File {
+		if file.Name == "collection.anki21b" && collection.Name == "collection.anki2" {
+			return "", errors.New("Anki package uses the newer collection format, export it with \"Support older Anki versions\" enabled")
+		}
+	}
+
+	reader, err := collection.Open()
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	fp, err := os.CreateTemp("", "yomichan-anki-*.db")
+	if err != nil {
+		return "", err
+	}
+	defer fp.Close()
+
+	if _, err := io.Copy(fp, reader); err != nil {
+		os.Remove(fp.Name())
+		return "", err
+	}
+
+	return fp.Name(), nil
+}
+
+func ankiReadZipFile(file *zip.File) ([]byte, error) {
+	reader, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// Reads the media files of an .apkg archive, which are stored under
+// numeric names and listed in the "media" JSON file.
+func ankiLoadPackageMedia(archive *zip.ReadCloser) (map[string][]byte, error) {
+	files := make(map[string]*zip.File)
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+
+	media := make(map[string][]byte)
+
+	index, ok := files["media"]
+	if !ok {
+		return media, nil
+	}
+
+	data, err := ankiReadZipFile(index)
+	if err != nil {
+		return nil, err
+	}
+
+	var names map[string]string
+	if err := json.Unmarshal(data, &names); err != nil {
+		// newer packages store the media list as protobuf
+		fmt.Println("Skipping unsupported Anki media list")
+		return media, nil
+	}
+
+	for number, name := range names {
+		file, ok := files[number]
+		if !ok {
+			continue
+		}
+		if media[name], err = ankiReadZipFile(file); err != nil {
+			return nil, err
+		}
+	}
+
+	return media, nil
+}
+
+// Reads the media files from the collection.media directory next to a
+// collection.anki2 file.
+func ankiLoadDirectoryMedia(collectionPath string) (map[string][]byte, error) {
+	media := make(map[string][]byte)
+
+	mediaPath := filepath.Join(filepath.Dir(collectionPath), "collection.media")
+	entries, err := os.ReadDir(mediaPath)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return media, nil
+		}
+		return nil, err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if media[entry.Name()], err = os.ReadFile(filepath.Join(mediaPath, entry.Name())); err != nil {
+			return nil, err
+		}
+	}
+
+	return media, nil
+}
+
+// Reads the name and field names of each note type, either from the
+// legacy JSON column of the col table or from the notetypes and fields
+// tables used by newer schema versions.
+func ankiLoadModels(db *sql.DB) (map[int64]ankiNoteType, error) {
+	models := make(map[int64]ankiNoteType)
+
+	var modelsJson string
+	if err := db.QueryRow("SELECT models FROM col").Scan(&modelsJson); err != nil {
+		return nil, err
+	}
+
+	if modelsJson != "" && modelsJson != "{}" {
+		var data map[string]ankiModel
+		if err := json.Unmarshal([]byte(modelsJson), &data); err != nil {
+			return nil, err
+		}
+
+		for id, model := range data {
+			modelId, err := strconv.ParseInt(id, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			fields := make([]string, len(model.Fields))
+			for _, field := range model.Fields {
+				if field.Ord >= 0 && field.Ord < len(fields) {
+					fields[field.Ord] = field.Name
+				}
+			}
+			models[modelId] = ankiNoteType{name: model.Name, fields: fields}
+		}
+
+		return models, nil
+	}
+
+	typeRows, err := db.Query("SELECT id, name FROM notetypes")
+	if err != nil {
+		return nil, err
+	}
+	defer typeRows.Close()
+
+	for typeRows.Next() {
+		var (
+			modelId int64
+			name    string
+		)
+		if err := typeRows.Scan(&modelId, &name); err != nil {
+			return nil, err
+		}
+		models[modelId] = ankiNoteType{name: name}
+	}
+	if err := typeRows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query("SELECT ntid, ord, name FROM fields ORDER BY ntid, ord")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			modelId int64
+			ord     int
+			name    string
+		)
+		if err := rows.Scan(&modelId, &ord, &name); err != nil {
+			return nil, err
+		}
+		model := models[modelId]
+		for len(model.fields) <= ord {
+			model.fields = append(model.fields, "")
+		}
+		model.fields[ord] = name
+		models[modelId] = model
+	}
+
+	return models, rows.Err()
+}
+
+func ankiLoadNotes(db *sql.DB) ([]ankiNote, error) {
+	rows, err := db.Query("SELECT mid, flds, tags FROM notes ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var (
+		notes    []ankiNote
+		sequence int
+	)
+
+	for rows.Next() {
+		var (
+			fields string
+			tags   string
+			note   ankiNote
+		)
+		if err := rows.Scan(&note.model, &fields, &tags); err != nil {
+			return nil, err
+		}
+
+		sequence++
+		note.sequence = sequence
+		note.fields = strings.Split(fields, "\x1f")
+		note.tags = strings.Fields(tags)
+
+		notes = append(notes, note)
+	}
+
+	return notes, rows.Err()
+}
+
+func ankiLoad(inputPath string) (ankiCollection, error) {
+	var (
+		collection     ankiCollection
+		collectionPath = inputPath
+		err            error
+	)
+
+	if strings.ToLower(filepath.Ext(inputPath)) == ".apkg" {
+		archive, err := zip.OpenReader(inputPath)
+		if err != nil {
+			return collection, err
+		}
+		defer archive.Close()
+
+		if collectionPath, err = ankiExtractCollection(archive); err != nil {
+			return collection, err
+		}
+		defer os.Remove(collectionPath)
+
+		if collection.media, err = ankiLoadPackageMedia(archive); err != nil {
+			return collection, err
+		}
+	} else if collection.media, err = ankiLoadDirectoryMedia(inputPath); err != nil {
+		return collection, err
+	}
+
+	db, err := sql.Open("sqlite3", collectionPath)
+	if err != nil {
+		return collection, err
+	}
+	defer db.Close()
+
+	if collection.models, err = ankiLoadModels(db); err != nil {
+		return collection, err
+	}
+
+	if collection.notes, err = ankiLoadNotes(db); err != nil {
+		return collection, err
+	}
+
+	return collection, nil
+}
+
+// Resolves field names or 1-based indices into field positions for a
+// note type. Returns false if a named field does not exist.
+func ankiFieldIndices(names []string, fields []string) ([]int, bool) {
+	var indices []int
+	for _, name := range names {
+		if index, err := strconv.Atoi(name); err == nil {
+			indices = append(indices, index-1)
+			continue
+		}
+
+		found := false
+		for i, field := range fields {
+			if strings.EqualFold(field, name) {
+				indices = append(indices, i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, false
+		}
+	}
+	return indices, true
+}
+
+func ankiFieldValues(note ankiNote, indices []int) []string {
+	var values []string
+	for _, index := range indices {
+		if index >= 0 && index < len(note.fields) {
+			values = append(values, note.fields[index])
+		}
+	}
+	return values
+}
+
+// Converts HTML field content into plain text, removing sound tags.
+func ankiFieldText(field string) string {
+	field = ankiSoundExp.ReplaceAllString(field, "")
+	return strings.TrimSpace(parseMarkup(field).text())
+}
+
+// Splits text written in the Anki furigana notation, such as
+// "日本[にほん] 語[ご]", into the expression and its reading.
+func ankiFurigana(text string) (string, string) {
+	if !ankiFuriganaExp.MatchString(text) {
+		return text, ""
+	}
+
+	expression := ankiFuriganaExp.ReplaceAllString(text, "$1")
+	reading := ankiFuriganaExp.ReplaceAllString(text, "$2")
+
+	return strings.ReplaceAll(expression, " ", ""), strings.ReplaceAll(reading, " ", "")
+}
+
+func ankiExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	collection, err := ankiLoad(inputPath)
+	if err != nil {
+		return err
+	}
+
+	fieldNames := make(map[string][]string)
+	for role, names := range ankiDefaultFields {
+		fieldNames[role] = names
+	}
+	for role, names := range options.Fields {
+		switch role {
+		case "expression", "reading", "glossary", "tags":
+			fieldNames[role] = names
+		default:
+			return fmt.Errorf("unsupported Anki field mapping: %s", role)
+		}
+	}
+
+	media := make(map[string][]byte)
+	converter := markupConverter{
+		resolveImage: func(src string) (any, bool) {
+			// Anki percent-encodes file names with spaces or other
+			// special characters in the fields
+			if name, err := url.PathUnescape(src); err == nil {
+				if _, ok := collection.media[src]; !ok {
+					src = name
+				}
+			}
+			data, ok := collection.media[src]
+			if !ok {
+				return nil, false
+			}
+			// media files are stored flat, so names with directories
+			// would otherwise become arbitrary paths in the archive
+			if path.Base(src) != src || src == "." || src == ".." {
+				return nil, false
+			}
+			mediaPath := "anki/" + src
+			media[mediaPath] = data
+			return map[string]any{"tag": "img", "path": mediaPath}, true
+		},
+	}
+
+	var (
+		terms          dbTermList
+		tagNames       []string
+		skippedModels  = make(map[int64]bool)
+		resolvedFields = make(map[int64]map[string][]int)
+	)
+
+	for _, note := range collection.notes {
+		indices, ok := resolvedFields[note.model]
+		if !ok {
+			indices = make(map[string][]int)
+			for role, names := range fieldNames {
+				if indices[role], ok = ankiFieldIndices(names, collection.models[note.model].fields); !ok {
+					indices = nil
+					break
+				}
+			}
+			resolvedFields[note.model] = indices
+		}
+
+		if indices == nil {
+			if !skippedModels[note.model] {
+				name := collection.models[note.model].name
+				if name == "" {
+					name = strconv.FormatInt(note.model, 10)
+				}
+				fmt.Printf("Skipping notes of type '%s' which lack the mapped fields\n", name)
+				skippedModels[note.model] = true
+			}
+			continue
+		}
+
+		var expression, reading string
+		if values := ankiFieldValues(note, indices["expression"]); len(values) > 0 {
+			expression, reading = ankiFurigana(ankiFieldText(values[0]))
+		}
+		if values := ankiFieldValues(note, indices["reading"]); len(values) > 0 {
+			if text := ankiFieldText(values[0]); text != "" {
+				_, reading = ankiFurigana(text)
+				if reading == "" {
+					reading = text
+				}
+			}
+		}
+
+		if expression == "" {
+			continue
+		}
+
+		term := dbTerm{
+			Expression: expression,
+			Reading:    reading,
+			Sequence:   note.sequence,
+		}
+
+		for _, field := range ankiFieldValues(note, indices["glossary"]) {
+			field = strings.TrimSpace(ankiSoundExp.ReplaceAllString(field, ""))
+			if field == "" {
+				continue
+			}
+			if content := converter.htmlContent(field); len(content) > 0 {
+				term.Glossary = append(term.Glossary, contentStructure(content...))
+			}
+		}
+
+		if len(term.Glossary) == 0 {
+			continue
+		}
+
+		tags := note.tags
+		if values := ankiFieldValues(note, indices["tags"]); len(values) > 0 {
+			tags = strings.Fields(ankiFieldText(values[0]))
+		}
+		term.addTermTags(tags...)
+		tagNames = appendStringUnique(tagNames, tags...)
+
+		terms = append(terms, term)
+	}
+
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	}
+
+	var tags dbTagList
+	for _, name := range tagNames {
+		tags = append(tags, dbTag{Name: name})
+	}
+
+	recordData := map[string]dbRecordList{
+		"term": terms.crush(),
+		"tag":  tags.crush(),
+	}
+
+	index := dbIndex{
+		Title:     title,
+		Revision:  "anki",
+		Sequenced: true,
+	}
+
+	return writeDbWithMedia(
+		outputPath,
+		index,
+		recordData,
+		media,
+		stride,
+		pretty,
+	)
+}
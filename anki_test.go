@@ -0,0 +1,86 @@
+package yomichan
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+)
+
+func TestAnkiFurigana(t *testing.T) {
+	tests := []struct {
+		text       string
+		expression string
+		reading    string
+	}{
+		{"日本[にほん]", "日本", "にほん"},
+		{"食[た]べる", "食べる", "たべる"},
+		{"日本[にほん] 語[ご]", "日本語", "にほんご"},
+		{"お 茶[ちゃ]", "お茶", "おちゃ"},
+		{"猫", "猫", ""},
+		{"", "", ""},
+	}
+
+	for _, test := range tests {
+		expression, reading := ankiFurigana(test.text)
+		if expression != test.expression || reading != test.reading {
+			t.Errorf("ankiFurigana(%q) = %q, %q, want %q, %q", test.text, expression, reading, test.expression, test.reading)
+		}
+	}
+}
+
+func TestAnkiFieldIndices(t *testing.T) {
+	fields := []string{"Word", "Reading", "Meaning", "Notes"}
+
+	tests := []struct {
+		names []string
+		want  []int
+		ok    bool
+	}{
+		{[]string{"1"}, []int{0}, true},
+		{[]string{"word", "MEANING"}, []int{0, 2}, true},
+		{[]string{"Meaning", "4"}, []int{2, 3}, true},
+		{[]string{"Meaning", "Sentence"}, nil, false},
+		{nil, nil, true},
+	}
+
+	for _, test := range tests {
+		got, ok := ankiFieldIndices(test.names, fields)
+		if ok != test.ok || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ankiFieldIndices(%q) = %v, %v, want %v, %v", test.names, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestAnkiLoadModels(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, query := range []string{
+		"CREATE TABLE col (models TEXT)",
+		"INSERT INTO col VALUES ('{}')",
+		"CREATE TABLE notetypes (id INTEGER, name TEXT)",
+		"INSERT INTO notetypes VALUES (1, 'Basic'), (2, 'Vocabulary')",
+		"CREATE TABLE fields (ntid INTEGER, ord INTEGER, name TEXT)",
+		"INSERT INTO fields VALUES (1, 0, 'Front'), (1, 1, 'Back'), (2, 1, 'Reading'), (2, 0, 'Word')",
+	} {
+		if _, err := db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	models, err := ankiLoadModels(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[int64]ankiNoteType{
+		1: {name: "Basic", fields: []string{"Front", "Back"}},
+		2: {name: "Vocabulary", fields: []string{"Word", "Reading"}},
+	}
+	if !reflect.DeepEqual(models, want) {
+		t.Errorf("ankiLoadModels() = %v, want %v", models, want)
+	}
+}
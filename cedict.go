@@ -0,0 +1,262 @@
+package yomichan
+
+import (
+	"bufio"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+type cedictEntry struct {
+	traditional string
+	simplified  string
+	pinyin      string
+	glosses     []string
+}
+
+var (
+	cedictLineExp      = regexp.MustCompile(`^(\S+)\s+(\S+)\s+\[([^\]]*)\]\s+/(.*)/\s*$`)
+	cedictReferenceExp = regexp.MustCompile(`([^\s,;:|\[\]()]+)(?:\|([^\s,;:|\[\]()]+))?\[([^\]]+)\]`)
+	cedictDateExp      = regexp.MustCompile(`^#!\s*date=(\d{4}-\d{2}-\d{2})`)
+)
+
+const cedictAttribution = "CC-CEDICT is licensed under the Creative Commons Attribution-ShareAlike 4.0 International License. See https://cc-cedict.org/wiki/"
+
+// Tone marks for each vowel, indexed by tone number (1-4).
+var cedictToneMarks = map[rune][]rune{
+	'a': []rune("āáǎà"),
+	'e': []rune("ēéěè"),
+	'i': []rune("īíǐì"),
+	'o': []rune("ōóǒò"),
+	'u': []rune("ūúǔù"),
+	'ü': []rune("ǖǘǚǜ"),
+	'A': []rune("ĀÁǍÀ"),
+	'E': []rune("ĒÉĚÈ"),
+	'I': []rune("ĪÍǏÌ"),
+	'O': []rune("ŌÓǑÒ"),
+	'U': []rune("ŪÚǓÙ"),
+	'Ü': []rune("ǕǗǙǛ"),
+}
+
+// Converts a numbered pinyin syllable such as "lu:4" into "lǜ". The
+// tone mark goes on "a" or "e" if present, on the "o" of "ou", and
+// otherwise on the last vowel. Tokens which are not syllables, such as
+// punctuation, are returned unchanged.
+func cedictSyllableToMarks(syllable string) string {
+	syllable = strings.ReplaceAll(syllable, "u:", "ü")
+	syllable = strings.ReplaceAll(syllable, "U:", "Ü")
+
+	if len(syllable) < 2 {
+		return syllable
+	}
+
+	tone := syllable[len(syllable)-1]
+	if tone < '1' || tone > '5' {
+		return syllable
+	}
+
+	runes := []rune(syllable[:len(syllable)-1])
+	if tone == '5' {
+		return string(runes)
+	}
+
+	position := -1
+	lower := strings.ToLower(string(runes))
+	if index := strings.IndexAny(lower, "ae"); index != -1 {
+		position = len([]rune(lower[:index]))
+	} else if index := strings.Index(lower, "ou"); index != -1 {
+		position = len([]rune(lower[:index]))
+	} else {
+		for i := len(runes) - 1; i >= 0; i-- {
+			if _, ok := cedictToneMarks[runes[i]]; ok {
+				position = i
+				break
+			}
+		}
+	}
+
+	if position == -1 {
+		return string(runes)
+	}
+
+	runes[position] = cedictToneMarks[runes[position]][tone-'1']
+	return string(runes)
+}
+
+func cedictPinyinToMarks(pinyin string) string {
+	syllables := strings.Fields(pinyin)
+	for i, syllable := range syllables {
+		syllables[i] = cedictSyllableToMarks(syllable)
+	}
+	return strings.Join(syllables, " ")
+}
+
+// Converts a gloss into structured content when it contains classifier
+// or variant references, such as "CL:個|个[ge4]" or
+// "variant of 喫|吃[chi1]", so that the referenced words can be looked
+// up. Other glosses are returned as plain text.
+func cedictGlossContent(gloss string) any {
+	if !strings.HasPrefix(gloss, "CL:") && !strings.Contains(gloss, "variant of ") {
+		return gloss
+	}
+
+	matches := cedictReferenceExp.FindAllStringSubmatchIndex(gloss, -1)
+	if len(matches) == 0 {
+		return gloss
+	}
+
+	var (
+		content  []any
+		position int
+		attr     = contentAttr{lang: ISOtoHTML["chi"]}
+	)
+
+	for _, match := range matches {
+		if match[0] > position {
+			content = append(content, gloss[position:match[0]])
+		}
+
+		traditional := gloss[match[2]:match[3]]
+		text := traditional
+		if match[4] != -1 {
+			text += "|" + gloss[match[4]:match[5]]
+		}
+		pinyin := cedictPinyinToMarks(gloss[match[6]:match[7]])
+
+		content = append(content, contentInternalLink(attr, traditional, text))
+		content = append(content, "["+pinyin+"]")
+
+		position = match[1]
+	}
+
+	if position < len(gloss) {
+		content = append(content, gloss[position:])
+	}
+
+	return contentStructure(content...)
+}
+
+func cedictParseLine(line string) (cedictEntry, bool) {
+	matches := cedictLineExp.FindStringSubmatch(line)
+	if matches == nil {
+		return cedictEntry{}, false
+	}
+
+	entry := cedictEntry{
+		traditional: matches[1],
+		simplified:  matches[2],
+		pinyin:      matches[3],
+	}
+
+	for _, gloss := range strings.Split(matches[4], "/") {
+		if gloss = strings.TrimSpace(gloss); gloss != "" {
+			entry.glosses = append(entry.glosses, gloss)
+		}
+	}
+
+	return entry, len(entry.glosses) > 0
+}
+
+func cedictExtractTerms(entry cedictEntry, sequence int) []dbTerm {
+	var glossary []any
+	for _, gloss := range entry.glosses {
+		glossary = append(glossary, cedictGlossContent(gloss))
+	}
+
+	expressions := []string{entry.traditional}
+	if entry.simplified != entry.traditional {
+		expressions = append(expressions, entry.simplified)
+	}
+
+	reading := cedictPinyinToMarks(entry.pinyin)
+
+	// entries for proper nouns have capitalized pinyin
+	var tags []string
+	if len(entry.pinyin) > 0 && unicode.IsUpper(rune(entry.pinyin[0])) {
+		tags = append(tags, "name")
+	}
+
+	var terms []dbTerm
+	for _, expression := range expressions {
+		term := dbTerm{
+			Expression: expression,
+			Reading:    reading,
+			Glossary:   glossary,
+			Sequence:   sequence,
+		}
+		term.addDefinitionTags(tags...)
+		terms = append(terms, term)
+	}
+
+	return terms
+}
+
+func cedictExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	fp, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	var (
+		terms    dbTermList
+		revision = "cedict"
+		scanner  = bufio.NewScanner(fp)
+		sequence int
+	)
+
+	for scanner.Scan() {
+		line := strings.TrimPrefix(scanner.Text(), "\uFEFF")
+
+		if strings.HasPrefix(line, "#") {
+			if matches := cedictDateExp.FindStringSubmatch(line); matches != nil {
+				revision += "." + matches[1]
+			}
+			continue
+		}
+
+		entry, ok := cedictParseLine(line)
+		if !ok {
+			continue
+		}
+
+		sequence++
+		terms = append(terms, cedictExtractTerms(entry, sequence)...)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if title == "" {
+		title = "CC-CEDICT"
+	}
+
+	tags := dbTagList{
+		dbTag{Name: "name", Category: "name", Order: 4, Notes: "proper noun"},
+	}
+
+	recordData := map[string]dbRecordList{
+		"term": terms.crush(),
+		"tag":  tags.crush(),
+	}
+
+	index := dbIndex{
+		Title:          title,
+		Revision:       revision,
+		Sequenced:      true,
+		Url:            "https://cc-cedict.org/wiki/",
+		Attribution:    cedictAttribution,
+		SourceLanguage: ISOtoHTML["chi"],
+		TargetLanguage: ISOtoHTML["eng"],
+	}
+
+	return writeDb(
+		outputPath,
+		index,
+		recordData,
+		stride,
+		pretty,
+	)
+}
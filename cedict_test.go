@@ -0,0 +1,33 @@
+package yomichan
+
+import "testing"
+
+func TestCedictPinyinToMarks(t *testing.T) {
+	tests := []struct {
+		pinyin string
+		want   string
+	}{
+		{"zhong1 guo2", "zhōng guó"},
+		{"ni3 hao3", "nǐ hǎo"},
+		{"lu:4 se4", "lǜ sè"},
+		{"nu:3 er2", "nǚ ér"},
+		{"Lu:3 xun4", "Lǚ xùn"},
+		{"dou1", "dōu"},
+		{"gui4", "guì"},
+		{"liu2", "liú"},
+		{"xue2 sheng5", "xué sheng"},
+		{"Ou1 zhou1", "Ōu zhōu"},
+		{"An1 hui1", "Ān huī"},
+		{"er4", "èr"},
+		{"r5", "r"},
+		{"m2", "m"},
+		{"A A zhi4 , B", "A A zhì , B"},
+		{"xx5", "xx"},
+	}
+
+	for _, test := range tests {
+		if got := cedictPinyinToMarks(test.pinyin); got != test.want {
+			t.Errorf("cedictPinyinToMarks(%q) = %q, want %q", test.pinyin, got, test.want)
+		}
+	}
+}
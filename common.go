@@ -21,6 +21,49 @@ const (
 	DefaultTitle    = ""
 )
 
+// ExportOptions holds settings which only apply to some of the
+// supported dictionary formats.
+type ExportOptions struct {
+	// EPWING subbook titles or 1-based indices to convert (all if empty)
+	Subbooks []string
+	// write each EPWING subbook to its own archive
+	SplitSubbooks bool
+	// skip EPWING subbooks without a compatible extractor
+	SkipUnsupported bool
+	// group EPWING terms sharing a headword across subbooks
+	MergeSubbooks bool
+	// note fields or columns (names or 1-based indices) holding each part
+	// of a term, keyed by role such as "expression" or "glossary"
+	Fields map[string][]string
+	// column delimiter of TSV/CSV files, by name or as a character
+	Delimiter string
+	// TSV/CSV files begin with a header row naming the columns
+	Header bool
+	// separator between multiple glosses within one TSV/CSV column
+	Separator string
+	// whether frequency values are ranks or occurrence counts
+	FrequencyMode string
+	// JMdict file used to segment corpus text into headwords, or to look
+	// up the readings of Tatoeba example words
+	Lexicon string
+	// minimum number of occurrences for corpus frequency records
+	MinCount int
+	// extra KANJIDIC reading types to add to the kanji stats
+	KanjiReadings []string
+	// KRADFILE and KRADFILE2 paths listing the components of each kanji
+	Kradfiles []string
+	// RADKFILE path listing the kanji containing each component
+	Radkfile string
+	// add KANJIDIC entries for variant characters without meanings
+	KanjiVariants bool
+	// KanjiVG XML file or SVG directory with stroke order diagrams
+	KanjiVG string
+	// color each stroke of the stroke order diagrams differently
+	KanjiVGColors bool
+	// add the stroke order diagrams to KANJIDIC as term entries
+	KanjiVGTerms bool
+}
+
 type dbRecord []any
 type dbRecordList []dbRecord
 
@@ -151,6 +194,10 @@ type dbIndex struct {
 	Url         string `json:"url"`
 	Description string `json:"description"`
 	Attribution string `json:"attribution"`
+
+	SourceLanguage string `json:"sourceLanguage,omitempty"`
+	TargetLanguage string `json:"targetLanguage,omitempty"`
+	FrequencyMode  string `json:"frequencyMode,omitempty"`
 }
 
 func (index *dbIndex) setDefaults() {
@@ -166,6 +213,13 @@ func (index *dbIndex) setDefaults() {
 }
 
 func writeDb(outputPath string, index dbIndex, recordData map[string]dbRecordList, stride int, pretty bool) error {
+	return writeDbWithMedia(outputPath, index, recordData, nil, stride, pretty)
+}
+
+// Writes a dictionary archive which also contains media files, such as
+// images referenced by structured content. Media is keyed by its path
+// inside the archive.
+func writeDbWithMedia(outputPath string, index dbIndex, recordData map[string]dbRecordList, media map[string][]byte, stride int, pretty bool) error {
 	var zbuff bytes.Buffer
 	zip := zip.NewWriter(&zbuff)
 
@@ -216,6 +270,17 @@ func writeDb(outputPath string, index dbIndex, recordData map[string]dbRecordLis
 		}
 	}
 
+	for path, data := range media {
+		zw, err := zip.Create(path)
+		if err != nil {
+			return err
+		}
+
+		if _, err := zw.Write(data); err != nil {
+			return err
+		}
+	}
+
 	index.setDefaults()
 	bytes, err := marshalJSON(index, pretty)
 	if err != nil {
@@ -296,15 +361,50 @@ func detectFormat(path string) (string, error) {
 		return "kanjifreq", nil
 	case ".termfreq":
 		return "termfreq", nil
+	case ".apkg", ".anki2", ".anki21":
+		return "anki", nil
+	case ".tsv", ".csv":
+		if filepath.Base(path) == "jpn_indices.csv" {
+			return "tatoeba", nil
+		}
+		return "tsv", nil
+	case ".pitch":
+		return "pitch", nil
+	case ".u8":
+		return "cedict", nil
+	case ".ifo":
+		return "stardict", nil
+	case ".dsl":
+		return "dsl", nil
+	case ".mdx":
+		return "mdict", nil
+	case ".jsonl":
+		return "wiktionary", nil
+	}
+
+	if strings.HasSuffix(path, ".dsl.dz") {
+		return "dsl", nil
+	}
+
+	if base := strings.ToLower(filepath.Base(path)); strings.HasPrefix(base, "kanjivg") && filepath.Ext(base) == ".xml" {
+		return "kanjivg", nil
+	}
+
+	if filepath.Ext(path) == ".json" && isJmdictSimplified(path) {
+		return "edict", nil
 	}
 
 	switch filepath.Base(path) {
 	case "JMdict", "JMdict.xml", "JMdict_e", "JMdict_e.xml", "JMdict_e_examp":
 		return "edict", nil
+	case "edict", "edict2", "edict2u":
+		return "edict2", nil
 	case "JMnedict", "JMnedict.xml":
 		return "enamdict", nil
 	case "kanjidic2", "kanjidic2.xml":
 		return "kanjidic", nil
+	case "accents.txt":
+		return "pitch", nil
 	}
 
 	info, err := os.Stat(path)
@@ -328,15 +428,32 @@ func detectFormat(path string) (string, error) {
 }
 
 func ExportDb(inputPath, outputPath, format, language, title string, stride int, pretty bool) error {
-	handlers := map[string]func(string, string, string, string, int, bool) error{
-		"edict":     jmdictExportDb,
-		"forms":     formsExportDb,
-		"enamdict":  jmnedictExportDb,
-		"epwing":    epwingExportDb,
-		"kanjidic":  kanjidicExportDb,
-		"rikai":     rikaiExportDb,
-		"kanjifreq": frequencyKanjiExportDb,
-		"termfreq":  frequencyTermsExportDb,
+	return ExportDbWithOptions(inputPath, outputPath, format, language, title, stride, pretty, ExportOptions{})
+}
+
+func ExportDbWithOptions(inputPath, outputPath, format, language, title string, stride int, pretty bool, options ExportOptions) error {
+	handlers := map[string]func(string, string, string, string, int, bool, ExportOptions) error{
+		"anki":       ankiExportDb,
+		"cedict":     cedictExportDb,
+		"corpusfreq": corpusFrequencyExportDb,
+		"dsl":        dslExportDb,
+		"edict":      jmdictExportDb,
+		"edict2":     edict2ExportDb,
+		"forms":      formsExportDb,
+		"enamdict":   jmnedictExportDb,
+		"epwing":     epwingExportDb,
+		"ipa":        ipaExportDb,
+		"kanjidic":   kanjidicExportDb,
+		"kanjivg":    kanjivgExportDb,
+		"mdict":      mdictExportDb,
+		"pitch":      pitchExportDb,
+		"rikai":      rikaiExportDb,
+		"stardict":   stardictExportDb,
+		"tatoeba":    tatoebaExportDb,
+		"tsv":        tsvExportDb,
+		"kanjifreq":  frequencyKanjiExportDb,
+		"termfreq":   frequencyTermsExportDb,
+		"wiktionary": wiktionaryExportDb,
 	}
 
 	var err error
@@ -351,5 +468,5 @@ func ExportDb(inputPath, outputPath, format, language, title string, stride int,
 		return errors.New("unrecognized dictionary format")
 	}
 
-	return handler(inputPath, outputPath, strings.ToLower(language), title, stride, pretty)
+	return handler(inputPath, outputPath, strings.ToLower(language), title, stride, pretty, options)
 }
@@ -12,8 +12,6 @@ type daijirinExtractor struct {
 	readGroupExp *regexp.Regexp
 	expVarExp    *regexp.Regexp
 	metaExp      *regexp.Regexp
-	v5Exp        *regexp.Regexp
-	v1Exp        *regexp.Regexp
 }
 
 func makeDaijirinExtractor() epwingExtractor {
@@ -22,8 +20,6 @@ func makeDaijirinExtractor() epwingExtractor {
 		readGroupExp: regexp.MustCompile(`[-・]+`),
 		expVarExp:    regexp.MustCompile(`\(([^\)]*)\)`),
 		metaExp:      regexp.MustCompile(`（([^）]*)）`),
-		v5Exp:        regexp.MustCompile(`(動.[四五](［[^］]+］)?)|(動..二)`),
-		v1Exp:        regexp.MustCompile(`(動..一)`),
 	}
 }
 
@@ -96,26 +92,26 @@ func (*daijirinExtractor) extractKanji(entry zig.BookEntry) []dbKanji {
 	return nil
 }
 
+func (*daijirinExtractor) extractTermMeta(entry zig.BookEntry, terms []dbTerm) []dbMeta {
+	// accent numbers such as [0] or [2] follow the reading on the
+	// first line of the entry text.
+	firstLine := strings.SplitN(entry.Text, "\n", 2)[0]
+	return epwingPitchMeta(terms, firstLine)
+}
+
 func (e *daijirinExtractor) exportRules(term *dbTerm, tags []string) {
-	for _, tag := range tags {
-		if tag == "形" {
-			term.addRules("adj-i")
-		} else if tag == "動サ変" && (strings.HasSuffix(term.Expression, "する") || strings.HasSuffix(term.Expression, "為る")) {
-			term.addRules("vs")
-		} else if term.Expression == "来る" {
-			term.addRules("vk")
-		} else if e.v5Exp.MatchString(tag) {
-			term.addRules("v5")
-		} else if e.v1Exp.MatchString(tag) {
-			term.addRules("v1")
-		}
-	}
+	term.addRules(japaneseGrammarRules(term.Expression, tags)...)
+	term.addDefinitionTags(japanesePartsOfSpeech(tags)...)
 }
 
 func (*daijirinExtractor) getRevision() string {
 	return "daijirin2"
 }
 
+func (*daijirinExtractor) getTags() dbTagList {
+	return japaneseGrammarTags()
+}
+
 func (*daijirinExtractor) getFontNarrow() map[int]string {
 	return map[int]string{
 		49441: "á",
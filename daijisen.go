@@ -14,8 +14,6 @@ type daijisenExtractor struct {
 	expVarExp    *regexp.Regexp
 	readGroupExp *regexp.Regexp
 	metaExp      *regexp.Regexp
-	v5Exp        *regexp.Regexp
-	v1Exp        *regexp.Regexp
 }
 
 func makeDaijisenExtractor() epwingExtractor {
@@ -26,8 +24,6 @@ func makeDaijisenExtractor() epwingExtractor {
 		expVarExp:    regexp.MustCompile(`（([^）]*)）`),
 		readGroupExp: regexp.MustCompile(`[‐・]+`),
 		metaExp:      regexp.MustCompile(`［([^］]*)］`),
-		v5Exp:        regexp.MustCompile(`(動.[四五](［[^］]+］)?)|(動..二)`),
-		v1Exp:        regexp.MustCompile(`(動..一)`),
 	}
 }
 
@@ -98,26 +94,23 @@ func (*daijisenExtractor) extractKanji(entry zig.BookEntry) []dbKanji {
 	return nil
 }
 
+func (*daijisenExtractor) extractTermMeta(entry zig.BookEntry, terms []dbTerm) []dbMeta {
+	return nil
+}
+
 func (e *daijisenExtractor) exportRules(term *dbTerm, tags []string) {
-	for _, tag := range tags {
-		if tag == "形" {
-			term.addRules("adj-i")
-		} else if tag == "動サ変" && (strings.HasSuffix(term.Expression, "する") || strings.HasSuffix(term.Expression, "為る")) {
-			term.addRules("vs")
-		} else if term.Expression == "来る" {
-			term.addRules("vk")
-		} else if e.v5Exp.MatchString(tag) {
-			term.addRules("v5")
-		} else if e.v1Exp.MatchString(tag) {
-			term.addRules("v1")
-		}
-	}
+	term.addRules(japaneseGrammarRules(term.Expression, tags)...)
+	term.addDefinitionTags(japanesePartsOfSpeech(tags)...)
 }
 
 func (*daijisenExtractor) getRevision() string {
 	return "daijisen2"
 }
 
+func (*daijisenExtractor) getTags() dbTagList {
+	return japaneseGrammarTags()
+}
+
 func (*daijisenExtractor) getFontNarrow() map[int]string {
 	return map[int]string{
 		0xa121: " ",
@@ -0,0 +1,447 @@
+package yomichan
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"golang.org/x/exp/slices"
+)
+
+type dslCard struct {
+	headwords []string
+	lines     []string
+}
+
+var (
+	dslCommentExp = regexp.MustCompile(`(?s)\{\{.*?\}\}`)
+	dslHeaderExp  = regexp.MustCompile(`^#([A-Z_]+)\s+"?(.*?)"?\s*$`)
+	dslMarginExp  = regexp.MustCompile(`^m([0-9]?)$`)
+
+	// Tags which are kept as data attributes so they can be styled.
+	dslDataNames = map[string]string{
+		"c":    "color",
+		"p":    "label",
+		"com":  "comment",
+		"ex":   "example",
+		"trn":  "translation",
+		"!trs": "translation",
+		"*":    "secondary",
+		"t":    "transcription",
+		"'":    "stress",
+	}
+)
+
+// Decodes the contents of a DSL file, which are usually UTF-16LE but
+// may also be UTF-16BE or UTF-8, with or without a byte order mark.
+func dslDecode(data []byte) (string, error) {
+	var order binary.ByteOrder
+
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		order = binary.LittleEndian
+		data = data[2:]
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		order = binary.BigEndian
+		data = data[2:]
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		data = data[3:]
+	case len(data) >= 2 && data[0] != 0 && data[1] == 0:
+		order = binary.LittleEndian
+	case len(data) >= 2 && data[0] == 0 && data[1] != 0:
+		order = binary.BigEndian
+	}
+
+	if order == nil {
+		if !utf8.Valid(data) {
+			return "", errors.New("unsupported DSL file encoding")
+		}
+		return string(data), nil
+	}
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[i*2:])
+	}
+
+	return string(utf16.Decode(units)), nil
+}
+
+func dslLoad(path string) (string, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fp.Close()
+
+	var reader io.Reader = fp
+	if strings.HasSuffix(path, ".dz") {
+		gzipReader, err := gzip.NewReader(fp)
+		if err != nil {
+			return "", err
+		}
+		reader = gzipReader
+	}
+
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return "", err
+	}
+
+	return dslDecode(data)
+}
+
+// Splits the contents of a DSL file into its header directives and
+// cards. A card consists of one or more headword lines followed by
+// indented body lines.
+func dslParse(text string) (map[string]string, []dslCard) {
+	text = dslCommentExp.ReplaceAllLiteralString(text, "")
+
+	var (
+		headers = make(map[string]string)
+		cards   []dslCard
+		card    *dslCard
+	)
+
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, "\r")
+
+		if strings.HasPrefix(line, "#") && len(cards) == 0 && card == nil {
+			if matches := dslHeaderExp.FindStringSubmatch(line); matches != nil {
+				headers[matches[1]] = matches[2]
+			}
+			continue
+		}
+
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		if line[0] == ' ' || line[0] == '\t' {
+			if card != nil {
+				card.lines = append(card.lines, strings.TrimSpace(line))
+			}
+			continue
+		}
+
+		if card == nil || len(card.lines) > 0 {
+			cards = append(cards, dslCard{})
+			card = &cards[len(cards)-1]
+		}
+		card.headwords = append(card.headwords, line)
+	}
+
+	return headers, cards
+}
+
+// Returns the expressions indexed by a DSL headword. Text in braces is
+// displayed but not indexed, and text in parentheses is optional, so
+// "go(es)" is indexed as both "go" and "goes".
+func dslHeadwordExpressions(headword string) []string {
+	expressions := []string{""}
+
+	var (
+		escaped  bool
+		braces   int
+		optional []string
+	)
+
+	for _, char := range headword {
+		switch {
+		case escaped:
+			escaped = false
+		case char == '\\':
+			escaped = true
+			continue
+		case char == '{':
+			braces++
+			continue
+		case char == '}':
+			if braces > 0 {
+				braces--
+			}
+			continue
+		case braces > 0:
+			continue
+		case char == '(':
+			optional = append(optional, "")
+			continue
+		case char == ')' && len(optional) > 0:
+			var expanded []string
+			for _, expression := range expressions {
+				expanded = append(expanded, expression, expression+optional[len(optional)-1])
+			}
+			optional = optional[:len(optional)-1]
+			expressions = expanded
+			continue
+		}
+
+		if braces > 0 {
+			continue
+		}
+
+		if len(optional) > 0 {
+			optional[len(optional)-1] += string(char)
+		} else {
+			for i := range expressions {
+				expressions[i] += string(char)
+			}
+		}
+	}
+
+	var results []string
+	for _, expression := range expressions {
+		expression = strings.Join(strings.Fields(expression), " ")
+		if expression != "" {
+			results = appendStringUnique(results, expression)
+		}
+	}
+
+	return results
+}
+
+// Parses a line of DSL markup into a tree of markupNode values. Tags
+// which are left open are closed at the end of the line, and
+// <<reference>> is treated the same as [ref]reference[/ref].
+func dslParseMarkup(line string) *markupNode {
+	root := &markupNode{}
+	stack := []*markupNode{root}
+
+	var text strings.Builder
+	flush := func() {
+		if text.Len() > 0 {
+			parent := stack[len(stack)-1]
+			parent.children = append(parent.children, text.String())
+			text.Reset()
+		}
+	}
+
+	openTag := func(tag string, param string) {
+		flush()
+		node := &markupNode{tag: tag, attrs: map[string]string{"param": param}}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, node)
+		stack = append(stack, node)
+	}
+
+	closeTag := func(tag string) {
+		flush()
+		for i := len(stack) - 1; i > 0; i-- {
+			name := stack[i].tag
+			if name == tag || tag == "m" && dslMarginExp.MatchString(name) {
+				stack = stack[:i]
+				break
+			}
+		}
+	}
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		char := runes[i]
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case char == '\\' && next != 0:
+			text.WriteRune(next)
+			i++
+		case char == '<' && next == '<':
+			openTag("ref", "")
+			i++
+		case char == '>' && next == '>':
+			closeTag("ref")
+			i++
+		case char == '[':
+			end := slices.Index(runes[i:], ']')
+			if end == -1 {
+				text.WriteRune(char)
+				continue
+			}
+
+			tag := string(runes[i+1 : i+end])
+			i += end
+
+			if strings.HasPrefix(tag, "/") {
+				closeTag(strings.TrimPrefix(tag, "/"))
+			} else {
+				name, param, _ := strings.Cut(tag, " ")
+				openTag(name, strings.Trim(param, `"`))
+			}
+		default:
+			text.WriteRune(char)
+		}
+	}
+	flush()
+
+	return root
+}
+
+func dslConvertChildren(node *markupNode) []any {
+	var contents []any
+	for _, child := range node.children {
+		switch v := child.(type) {
+		case string:
+			contents = append(contents, v)
+		case *markupNode:
+			if content := dslConvertNode(v); content != nil {
+				contents = append(contents, content)
+			}
+		}
+	}
+	return contents
+}
+
+func dslConvertNode(node *markupNode) any {
+	var attr contentAttr
+
+	switch node.tag {
+	case "s", "video":
+		// media files are not bundled
+		return nil
+	case "lang":
+		attr.lang, _ = dslLanguageCode(node.attrs["param"])
+	}
+
+	children := dslConvertChildren(node)
+	if len(children) == 0 {
+		return nil
+	}
+
+	if matches := dslMarginExp.FindStringSubmatch(node.tag); matches != nil {
+		attr.marginLeft, _ = strconv.Atoi(matches[1])
+		return contentDiv(attr, children...)
+	}
+
+	if name, ok := dslDataNames[node.tag]; ok {
+		attr.data = map[string]string{"dsl": name}
+	}
+
+	switch node.tag {
+	case "b":
+		attr.fontWeight = "bold"
+	case "i":
+		attr.fontStyle = "italic"
+	case "u":
+		attr.textDecorationLine = []string{"underline"}
+	case "sup":
+		attr.verticalAlign = "super"
+		attr.fontSize = "smaller"
+	case "sub":
+		attr.verticalAlign = "sub"
+		attr.fontSize = "smaller"
+	case "p", "com":
+		attr.fontStyle = "italic"
+	case "ref":
+		query := strings.TrimSpace(node.text())
+		if query == "" {
+			return nil
+		}
+		return contentInternalLink(attr, query, children...)
+	}
+
+	return contentSpan(attr, children...)
+}
+
+// Maps a DSL language name (e.g. "English") to an HTML language code.
+func dslLanguageCode(name string) (string, bool) {
+	name = strings.TrimPrefix(strings.TrimPrefix(name, "name="), "id=")
+	name = strings.ToLower(strings.Trim(name, `"`))
+	if code, ok := langNameToCode[name]; ok {
+		if lang, ok := ISOtoHTML[code]; ok {
+			return lang, true
+		}
+	}
+	return "", false
+}
+
+func dslExtractTerms(card dslCard, lang string, sequence int) []dbTerm {
+	var contents []any
+	for _, line := range card.lines {
+		lineContents := dslConvertChildren(dslParseMarkup(line))
+		if len(lineContents) == 0 {
+			continue
+		}
+
+		// lines without an [m] tag are still displayed on their own
+		if len(lineContents) == 1 {
+			if content, ok := lineContents[0].(map[string]any); ok && content["tag"] == "div" {
+				contents = append(contents, content)
+				continue
+			}
+		}
+		contents = append(contents, contentDiv(contentAttr{}, lineContents...))
+	}
+
+	if len(contents) == 0 {
+		return nil
+	}
+
+	glossary := contentStructure(contents...)
+	if lang != "" {
+		glossary = contentStructure(contentDiv(contentAttr{lang: lang}, contents...))
+	}
+
+	var terms []dbTerm
+	for _, headword := range card.headwords {
+		for _, expression := range dslHeadwordExpressions(headword) {
+			terms = append(terms, dbTerm{
+				Expression: expression,
+				Glossary:   []any{glossary},
+				Sequence:   sequence,
+			})
+		}
+	}
+
+	return terms
+}
+
+func dslExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	text, err := dslLoad(inputPath)
+	if err != nil {
+		return err
+	}
+
+	headers, cards := dslParse(text)
+	if len(cards) == 0 {
+		return errors.New("no cards found in DSL file")
+	}
+
+	lang, _ := dslLanguageCode(headers["CONTENTS_LANGUAGE"])
+
+	var terms dbTermList
+	for i, card := range cards {
+		terms = append(terms, dslExtractTerms(card, lang, i+1)...)
+	}
+
+	if title == "" {
+		title = headers["NAME"]
+	}
+
+	recordData := map[string]dbRecordList{
+		"term": terms.crush(),
+	}
+
+	index := dbIndex{
+		Title:     title,
+		Revision:  "dsl",
+		Sequenced: true,
+	}
+
+	return writeDb(
+		outputPath,
+		index,
+		recordData,
+		stride,
+		pretty,
+	)
+}
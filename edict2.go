@@ -0,0 +1,398 @@
+package yomichan
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/exp/slices"
+	"golang.org/x/text/encoding/japanese"
+)
+
+type edict2Form struct {
+	text         string
+	tags         []string
+	restrictions []string
+}
+
+type edict2Sense struct {
+	tags         []string
+	restrictions []string
+	glosses      []string
+}
+
+type edict2Entry struct {
+	kanji    []edict2Form
+	readings []edict2Form
+	senses   []edict2Sense
+	priority bool
+	sequence int
+}
+
+var (
+	edict2HeadwordExp = regexp.MustCompile(`^([^\[]+?)\s*(?:\[([^\]]*)\])?$`)
+	edict2GroupExp    = regexp.MustCompile(`^\s*(?:\(([^)]*)\)|\{([^}]*)\})`)
+	edict2FormTagExp  = regexp.MustCompile(`\(([^)]*)\)`)
+	edict2SequenceExp = regexp.MustCompile(`^EntL(\d+)X?$`)
+	edict2DateExp     = regexp.MustCompile(`\d{4}-\d{2}-\d{2}`)
+	edict2NumberExp   = regexp.MustCompile(`^\d+$`)
+)
+
+// Tags which start a new set of parts of speech for the following senses.
+var edict2PartsOfSpeech = func() map[string]bool {
+	names := make(map[string]bool)
+	for _, tag := range knownEntityTags() {
+		if tag.Category == "partOfSpeech" {
+			names[tag.Name] = true
+		}
+	}
+	return names
+}()
+
+// Tags which may appear in parenthesized groups: the JMdict entities,
+// which include newer tags such as v1-s or adj-ix, along with the older
+// tags understood by rikai.
+var edict2KnownTags = func() map[string]bool {
+	names := make(map[string]bool)
+	for _, tag := range knownEntityTags() {
+		names[tag.Name] = true
+	}
+	return names
+}()
+
+func edict2IsTag(tag string) bool {
+	return edict2KnownTags[tag] || rikaiTagParsed(tag)
+}
+
+// Parses a list of headword forms such as "漢字;漢字(iK)" or
+// "かんじ(漢字)(P);カンジ". Parenthesized groups containing
+// Japanese text restrict a reading to the given kanji forms.
+func edict2ParseForms(text string) []edict2Form {
+	var forms []edict2Form
+	for _, part := range strings.Split(text, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		form := edict2Form{text: part}
+		if index := strings.Index(part, "("); index != -1 {
+			form.text = part[:index]
+			for _, matches := range edict2FormTagExp.FindAllStringSubmatch(part[index:], -1) {
+				values := strings.Split(matches[1], ",")
+				if utf8.RuneCountInString(matches[1]) == len(matches[1]) {
+					form.tags = append(form.tags, values...)
+				} else {
+					form.restrictions = append(form.restrictions, values...)
+				}
+			}
+		}
+
+		forms = append(forms, form)
+	}
+	return forms
+}
+
+// Consumes the parenthesized groups at the start of a gloss. Returns
+// the tags, the sense number (0 if none), the sense restrictions and
+// the remaining gloss text.
+func edict2ParseGloss(gloss string) (tags []string, senseNumber int, restrictions []string, text string) {
+	for {
+		matches := edict2GroupExp.FindStringSubmatch(gloss)
+		if matches == nil {
+			break
+		}
+
+		group := matches[1]
+		if matches[2] != "" {
+			// field tags, e.g. {comp}
+			group = matches[2]
+		}
+
+		if edict2NumberExp.MatchString(group) {
+			senseNumber, _ = strconv.Atoi(group)
+		} else if strings.HasSuffix(group, " only") {
+			restrictions = append(restrictions, strings.Split(strings.TrimSuffix(group, " only"), ",")...)
+		} else {
+			values := strings.Split(group, ",")
+			for i := range values {
+				values[i] = strings.TrimSpace(values[i])
+			}
+			if !slices.ContainsFunc(values, func(value string) bool { return !edict2IsTag(value) }) {
+				tags = append(tags, values...)
+			} else {
+				// not a tag list, e.g. "(See 漢字)", so keep it in the gloss
+				break
+			}
+		}
+
+		gloss = gloss[len(matches[0]):]
+	}
+
+	return tags, senseNumber, restrictions, strings.TrimSpace(gloss)
+}
+
+// Parses a line such as
+// "漢字 [かんじ] /(n) (1) Chinese characters/(2) kanji/(P)/EntL1234567X/".
+// Parts of speech carry over to following senses until new ones are
+// given, as in JMdict.
+func edict2ParseLine(line string) (edict2Entry, bool) {
+	headword, definition, ok := strings.Cut(line, " /")
+	if !ok {
+		return edict2Entry{}, false
+	}
+
+	matches := edict2HeadwordExp.FindStringSubmatch(strings.TrimSpace(headword))
+	if matches == nil {
+		return edict2Entry{}, false
+	}
+
+	var entry edict2Entry
+	if matches[2] == "" {
+		entry.readings = edict2ParseForms(matches[1])
+	} else {
+		entry.kanji = edict2ParseForms(matches[1])
+		entry.readings = edict2ParseForms(matches[2])
+	}
+
+	var (
+		sense          *edict2Sense
+		partsOfSpeech  []string
+		segments       = strings.Split(strings.TrimSuffix(definition, "/"), "/")
+		startNewSense  = true
+		previousNumber int
+	)
+
+	for _, segment := range segments {
+		segment = strings.TrimSpace(segment)
+		if segment == "" {
+			continue
+		}
+
+		if segment == "(P)" {
+			entry.priority = true
+			continue
+		}
+
+		if sequenceMatches := edict2SequenceExp.FindStringSubmatch(segment); sequenceMatches != nil {
+			entry.sequence, _ = strconv.Atoi(sequenceMatches[1])
+			continue
+		}
+
+		tags, senseNumber, restrictions, text := edict2ParseGloss(segment)
+		if senseNumber != 0 && senseNumber != previousNumber {
+			startNewSense = true
+			previousNumber = senseNumber
+		}
+
+		var posTags []string
+		for _, tag := range tags {
+			if edict2PartsOfSpeech[tag] {
+				posTags = append(posTags, tag)
+			}
+		}
+		if len(posTags) > 0 {
+			partsOfSpeech = posTags
+		}
+
+		if startNewSense {
+			entry.senses = append(entry.senses, edict2Sense{})
+			sense = &entry.senses[len(entry.senses)-1]
+			sense.tags = appendStringUnique(sense.tags, partsOfSpeech...)
+			startNewSense = false
+		}
+
+		sense.tags = appendStringUnique(sense.tags, tags...)
+		sense.restrictions = append(sense.restrictions, restrictions...)
+		if text != "" {
+			sense.glosses = append(sense.glosses, text)
+		}
+	}
+
+	return entry, len(entry.readings) > 0 && len(entry.senses) > 0
+}
+
+func edict2ExtractTerms(entry edict2Entry) []dbTerm {
+	type headword struct {
+		expression string
+		reading    string
+		tags       []string
+	}
+
+	var headwords []headword
+	for _, reading := range entry.readings {
+		if len(entry.kanji) == 0 {
+			headwords = append(headwords, headword{reading.text, reading.text, reading.tags})
+			continue
+		}
+		for _, kanji := range entry.kanji {
+			if len(reading.restrictions) > 0 && !slices.Contains(reading.restrictions, kanji.text) {
+				continue
+			}
+			tags := appendStringUnique(append([]string{}, kanji.tags...), reading.tags...)
+			headwords = append(headwords, headword{kanji.text, reading.text, tags})
+		}
+	}
+
+	// when individual forms are marked with (P), the entry-level (P)
+	// only applies to those forms
+	formPriority := false
+	for _, headword := range headwords {
+		formPriority = formPriority || slices.Contains(headword.tags, "P")
+	}
+
+	var terms []dbTerm
+	for _, headword := range headwords {
+		for _, sense := range entry.senses {
+			if len(sense.restrictions) > 0 && !slices.Contains(sense.restrictions, headword.expression) && !slices.Contains(sense.restrictions, headword.reading) {
+				continue
+			}
+			if len(sense.glosses) == 0 {
+				continue
+			}
+
+			term := dbTerm{
+				Expression: headword.expression,
+				Reading:    headword.reading,
+				Sequence:   entry.sequence,
+			}
+
+			for _, gloss := range sense.glosses {
+				term.Glossary = append(term.Glossary, gloss)
+			}
+
+			term.addDefinitionTags(sense.tags...)
+			for _, tag := range headword.tags {
+				if edict2IsTag(tag) && tag != "P" {
+					term.addDefinitionTags(tag)
+				}
+			}
+			if formPriority && slices.Contains(headword.tags, "P") || !formPriority && entry.priority {
+				term.addDefinitionTags("P")
+			}
+
+			rikaiBuildRules(&term)
+			rikaiBuildScore(&term)
+
+			terms = append(terms, term)
+		}
+	}
+
+	return terms
+}
+
+// Builds the tag bank for the tags used by the exported terms, taking
+// the categories of the matching JMdict entities, or of the rikai
+// tags for those which are not entities, such as P.
+func edict2Tags(terms dbTermList) dbTagList {
+	var tagNames []string
+	for _, term := range terms {
+		tagNames = appendStringUnique(tagNames, term.DefinitionTags...)
+		tagNames = appendStringUnique(tagNames, term.TermTags...)
+	}
+
+	knownTags := append(knownEntityTags(), rikaiTags()...)
+
+	var tags dbTagList
+	for _, name := range tagNames {
+		tag := dbTag{Name: name}
+		if idx := slices.IndexFunc(knownTags, func(t dbTag) bool { return t.Name == name }); idx != -1 {
+			tag = knownTags[idx]
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+func edict2ExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	data, err := os.ReadFile(inputPath)
+	if err != nil {
+		return err
+	}
+
+	// the original files are EUC-JP encoded, but UTF-8 versions exist
+	if !utf8.Valid(data) {
+		if data, err = japanese.EUCJP.NewDecoder().Bytes(data); err != nil {
+			return err
+		}
+	}
+
+	type edict2Line struct {
+		entry  edict2Entry
+		number int
+	}
+
+	var (
+		lines       []edict2Line
+		maxSequence int
+		revision    = "edict2"
+		scanner     = bufio.NewScanner(bytes.NewReader(data))
+	)
+
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := scanner.Text()
+
+		// the first line is a header containing the creation date
+		if lineNumber == 1 && strings.HasPrefix(line, "　？？？") {
+			if date := edict2DateExp.FindString(line); date != "" {
+				revision += "." + date
+			}
+			continue
+		}
+
+		entry, ok := edict2ParseLine(line)
+		if !ok {
+			continue
+		}
+		if entry.sequence > maxSequence {
+			maxSequence = entry.sequence
+		}
+
+		lines = append(lines, edict2Line{entry, lineNumber})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	// entries without an EntL sequence number are numbered after the
+	// highest one in the file, so that they cannot collide
+	var terms dbTermList
+	for _, line := range lines {
+		if line.entry.sequence == 0 {
+			line.entry.sequence = maxSequence + line.number
+		}
+		terms = append(terms, edict2ExtractTerms(line.entry)...)
+	}
+
+	if title == "" {
+		title = "EDICT2"
+	}
+
+	recordData := map[string]dbRecordList{
+		"term": terms.crush(),
+		"tag":  edict2Tags(terms).crush(),
+	}
+
+	index := dbIndex{
+		Title:       title,
+		Revision:    revision,
+		Sequenced:   true,
+		Attribution: edrdgAttribution,
+	}
+
+	return writeDb(
+		outputPath,
+		index,
+		recordData,
+		stride,
+		pretty,
+	)
+}
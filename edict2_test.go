@@ -0,0 +1,68 @@
+package yomichan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEdict2ParseGloss(t *testing.T) {
+	tests := []struct {
+		gloss        string
+		tags         []string
+		senseNumber  int
+		restrictions []string
+		text         string
+	}{
+		{"(n) (1) Chinese characters", []string{"n"}, 1, nil, "Chinese characters"},
+		{"(v1-s,vt) to give", []string{"v1-s", "vt"}, 0, nil, "to give"},
+		{"(adj-ix) good", []string{"adj-ix"}, 0, nil, "good"},
+		{"(2) {comp} compiler", []string{"comp"}, 2, nil, "compiler"},
+		{"(漢字 only) kanji", nil, 0, []string{"漢字"}, "kanji"},
+		{"(漢字,感じ only) feeling", nil, 0, []string{"漢字", "感じ"}, "feeling"},
+		{"(See 漢字) reference", nil, 0, nil, "(See 漢字) reference"},
+		{"(n,foo) text", nil, 0, nil, "(n,foo) text"},
+		{"plain gloss", nil, 0, nil, "plain gloss"},
+	}
+
+	for _, test := range tests {
+		tags, senseNumber, restrictions, text := edict2ParseGloss(test.gloss)
+		if !reflect.DeepEqual(tags, test.tags) || senseNumber != test.senseNumber || !reflect.DeepEqual(restrictions, test.restrictions) || text != test.text {
+			t.Errorf("edict2ParseGloss(%q) = %q, %d, %q, %q, want %q, %d, %q, %q", test.gloss, tags, senseNumber, restrictions, text, test.tags, test.senseNumber, test.restrictions, test.text)
+		}
+	}
+}
+
+func TestEdict2Sequences(t *testing.T) {
+	entry, ok := edict2ParseLine("漢字 [かんじ] /(n) Chinese characters/(P)/EntL1234567X/")
+	if !ok || entry.sequence != 1234567 || !entry.priority {
+		t.Errorf("edict2ParseLine() = %+v, %v, want sequence 1234567 with priority", entry, ok)
+	}
+
+	entry, ok = edict2ParseLine("仮名 [かな] /(n) kana/")
+	if !ok || entry.sequence != 0 {
+		t.Errorf("edict2ParseLine() = %+v, %v, want sequence 0", entry, ok)
+	}
+}
+
+func TestEdict2Tags(t *testing.T) {
+	entry, _ := edict2ParseLine("漢字 [かんじ] /(n) {comp} (1) Chinese characters/(2) (uk) kanji/(P)/EntL1234567X/")
+
+	categories := make(map[string]string)
+	for _, tag := range edict2Tags(edict2ExtractTerms(entry)) {
+		categories[tag.Name] = tag.Category
+	}
+
+	for name, category := range map[string]string{"n": "partOfSpeech", "P": "popular"} {
+		if categories[name] != category {
+			t.Errorf("edict2Tags() category of %q = %q, want %q", name, categories[name], category)
+		}
+	}
+	for _, name := range []string{"comp", "uk"} {
+		if _, ok := categories[name]; !ok {
+			t.Errorf("edict2Tags() is missing %q", name)
+		}
+	}
+	if _, ok := categories["id"]; ok {
+		t.Errorf("edict2Tags() includes the unused tag \"id\"")
+	}
+}
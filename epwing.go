@@ -1,30 +1,47 @@
 package yomichan
 
 import (
+	"errors"
 	"fmt"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
 
 	zig "foosoft.net/projects/zero-epwing-go"
+	"golang.org/x/exp/slices"
 )
 
 type epwingExtractor interface {
 	extractTerms(entry zig.BookEntry, sequence int) []dbTerm
 	extractKanji(entry zig.BookEntry) []dbKanji
+	// terms holds the terms already extracted from the same entry
+	extractTermMeta(entry zig.BookEntry, terms []dbTerm) []dbMeta
+	getTags() dbTagList
 	getFontNarrow() map[int]string
 	getFontWide() map[int]string
 	getRevision() string
 }
 
-func epwingExportDb(inputPath, outputPath, language, title string, stride int, pretty bool) error {
-	book, err := zig.Load(inputPath)
-	if err != nil {
-		return err
-	}
+type epwingSubbookData struct {
+	title    string
+	revision string
+	terms    dbTermList
+	kanji    dbKanjiList
+	termMeta dbMetaList
+	tags     dbTagList
+}
 
-	translateExp := regexp.MustCompile(`{{([nw])_(\d+)}}`)
-	epwingExtractors := map[string]epwingExtractor{
+// EpwingSubbook describes a subbook of an EPWING book as reported by
+// ListEpwingSubbooks.
+type EpwingSubbook struct {
+	Title     string
+	Entries   int
+	Supported bool
+}
+
+func makeEpwingExtractors() map[string]epwingExtractor {
+	return map[string]epwingExtractor{
 		"三省堂　スーパー大辞林":    makeDaijirinExtractor(),
 		"大辞泉":            makeDaijisenExtractor(),
 		"明鏡国語辞典":         makeMeikyouExtractor(),
@@ -35,61 +52,203 @@ func epwingExportDb(inputPath, outputPath, language, title string, stride int, p
 		"学研国語大辞典":        makeGakkenExtractor(),
 		"古語辞典":           makeGakkenExtractor(),
 		"故事ことわざ辞典":       makeGakkenExtractor(),
-		"学研漢和大字典":        makeGakkenExtractor(),
+		"学研漢和大字典":        makeGakkenKanjiExtractor(),
 		"小学館２":           makeShougakukan2Extractor(),
 	}
+}
 
-	var (
-		terms     dbTermList
-		kanji     dbKanjiList
-		revisions []string
-		titles    []string
-		sequence  int
-	)
+// ListEpwingSubbooks returns the subbooks of the EPWING book at
+// inputPath, reporting whether each one has a compatible extractor.
+func ListEpwingSubbooks(inputPath string) ([]EpwingSubbook, error) {
+	book, err := zig.Load(inputPath)
+	if err != nil {
+		return nil, err
+	}
 
+	epwingExtractors := makeEpwingExtractors()
+
+	var subbooks []EpwingSubbook
 	for _, subbook := range book.Subbooks {
-		if extractor, ok := epwingExtractors[subbook.Title]; ok {
-			fontNarrow := extractor.getFontNarrow()
-			fontWide := extractor.getFontWide()
-
-			translate := func(str string) string {
-				for _, matches := range translateExp.FindAllStringSubmatch(str, -1) {
-					var font map[int]string
-					if matches[1] == "n" {
-						font = fontNarrow
-					} else {
-						font = fontWide
-					}
-
-					code, _ := strconv.Atoi(matches[2])
-					replacement, ok := font[code]
-					if !ok {
-						replacement = "�"
-					}
-
-					str = strings.Replace(str, matches[0], replacement, -1)
-				}
-				pattern := regexp.MustCompile("\n+")
-				str = pattern.ReplaceAllLiteralString(str, "\n")
-
-				return str
+		_, supported := epwingExtractors[subbook.Title]
+		subbooks = append(subbooks, EpwingSubbook{
+			Title:     subbook.Title,
+			Entries:   len(subbook.Entries),
+			Supported: supported,
+		})
+	}
+
+	return subbooks, nil
+}
+
+// Returns the indices of the subbooks matching the selection, which
+// may contain subbook titles or 1-based subbook indices.
+func epwingSelectSubbooks(book *zig.Book, selection []string) ([]int, error) {
+	var indices []int
+	if len(selection) == 0 {
+		for i := range book.Subbooks {
+			indices = append(indices, i)
+		}
+		return indices, nil
+	}
+
+	for _, selected := range selection {
+		selected = strings.TrimSpace(selected)
+		index := slices.IndexFunc(book.Subbooks, func(s zig.BookSubbook) bool { return s.Title == selected })
+		if index == -1 {
+			if number, err := strconv.Atoi(selected); err == nil && number >= 1 && number <= len(book.Subbooks) {
+				index = number - 1
+			} else {
+				return nil, fmt.Errorf("failed to find subbook '%s'", selected)
+			}
+		}
+		if !slices.Contains(indices, index) {
+			indices = append(indices, index)
+		}
+	}
+
+	return indices, nil
+}
+
+func epwingExtractSubbook(subbook zig.BookSubbook, extractor epwingExtractor, sequence *int) epwingSubbookData {
+	translateExp := regexp.MustCompile(`{{([nw])_(\d+)}}`)
+	fontNarrow := extractor.getFontNarrow()
+	fontWide := extractor.getFontWide()
+
+	translate := func(str string) string {
+		for _, matches := range translateExp.FindAllStringSubmatch(str, -1) {
+			var font map[int]string
+			if matches[1] == "n" {
+				font = fontNarrow
+			} else {
+				font = fontWide
+			}
+
+			code, _ := strconv.Atoi(matches[2])
+			replacement, ok := font[code]
+			if !ok {
+				replacement = "�"
 			}
 
-			for _, entry := range subbook.Entries {
-				entry.Heading = translate(entry.Heading)
-				entry.Text = translate(entry.Text)
+			str = strings.Replace(str, matches[0], replacement, -1)
+		}
+		pattern := regexp.MustCompile("\n+")
+		str = pattern.ReplaceAllLiteralString(str, "\n")
+
+		return str
+	}
+
+	data := epwingSubbookData{
+		title:    subbook.Title,
+		revision: extractor.getRevision(),
+		tags:     extractor.getTags(),
+	}
+
+	for _, entry := range subbook.Entries {
+		entry.Heading = translate(entry.Heading)
+		entry.Text = translate(entry.Text)
+
+		terms := extractor.extractTerms(entry, *sequence)
+		data.terms = append(data.terms, terms...)
+		data.kanji = append(data.kanji, extractor.extractKanji(entry)...)
+		data.termMeta = append(data.termMeta, extractor.extractTermMeta(entry, terms)...)
+
+		*sequence++
+	}
+
+	return data
+}
+
+// Returns a tag name identifying terms which came from a subbook.
+func epwingSubbookTagName(title string) string {
+	return strings.Join(strings.Fields(title), "_")
+}
+
+// Groups terms which share an expression and reading across subbooks
+// under a single sequence number, so that Yomichan displays them as
+// one entry. Terms from the same source entry keep sharing a sequence
+// number, exact duplicates are dropped and every definition is tagged
+// with the subbook it came from.
+func epwingMergeTerms(subbooks []epwingSubbookData) (dbTermList, dbTagList) {
+	type entryID struct {
+		subbook  int
+		sequence int
+	}
+
+	var (
+		terms           dbTermList
+		tags            dbTagList
+		sequence        int
+		keyToSequence   = make(map[string]int)
+		entryToSequence = make(map[entryID]int)
+		seenTerms       = make(map[string]bool)
+	)
+
+	for i, subbook := range subbooks {
+		tagName := epwingSubbookTagName(subbook.title)
+		tags = append(tags, dbTag{Name: tagName, Category: "dictionary", Notes: subbook.title})
 
-				terms = append(terms, extractor.extractTerms(entry, sequence)...)
-				kanji = append(kanji, extractor.extractKanji(entry)...)
+		for _, term := range subbook.terms {
+			key := term.Expression + "␞" + term.Reading
+			termKey := key + "␞" + fmt.Sprint(term.Glossary)
+			if seenTerms[termKey] {
+				continue
+			}
+			seenTerms[termKey] = true
 
+			id := entryID{i, term.Sequence}
+			mergedSequence, ok := keyToSequence[key]
+			if !ok {
+				mergedSequence, ok = entryToSequence[id]
+			}
+			if !ok {
 				sequence++
+				mergedSequence = sequence
+			}
+			if _, ok := keyToSequence[key]; !ok {
+				keyToSequence[key] = mergedSequence
+			}
+			if _, ok := entryToSequence[id]; !ok {
+				entryToSequence[id] = mergedSequence
 			}
 
-			revisions = append(revisions, extractor.getRevision())
-			titles = append(titles, subbook.Title)
-		} else {
-			return fmt.Errorf("failed to find compatible extractor for '%s'", subbook.Title)
+			term.Sequence = mergedSequence
+			term.DefinitionTags = append([]string{}, term.DefinitionTags...)
+			term.addDefinitionTags(tagName)
+			terms = append(terms, term)
+		}
+	}
+
+	return terms, tags
+}
+
+func epwingWriteDb(outputPath string, title string, subbooks []epwingSubbookData, merge bool, stride int, pretty bool) error {
+	var (
+		terms     dbTermList
+		kanji     dbKanjiList
+		termMeta  dbMetaList
+		tags      dbTagList
+		revisions []string
+		titles    []string
+	)
+
+	if merge {
+		terms, tags = epwingMergeTerms(subbooks)
+	}
+
+	for _, subbook := range subbooks {
+		if !merge {
+			terms = append(terms, subbook.terms...)
 		}
+		kanji = append(kanji, subbook.kanji...)
+		termMeta = append(termMeta, subbook.termMeta...)
+		for _, tag := range subbook.tags {
+			if !slices.ContainsFunc(tags, func(t dbTag) bool { return t.Name == tag.Name }) {
+				tags = append(tags, tag)
+			}
+		}
+
+		revisions = append(revisions, subbook.revision)
+		titles = append(titles, subbook.title)
 	}
 
 	if title == "" {
@@ -97,8 +256,10 @@ func epwingExportDb(inputPath, outputPath, language, title string, stride int, p
 	}
 
 	recordData := map[string]dbRecordList{
-		"kanji": kanji.crush(),
-		"term":  terms.crush(),
+		"kanji":     kanji.crush(),
+		"term":      terms.crush(),
+		"term_meta": termMeta.crush(),
+		"tag":       tags.crush(),
 	}
 
 	index := dbIndex{
@@ -115,3 +276,62 @@ func epwingExportDb(inputPath, outputPath, language, title string, stride int, p
 		pretty,
 	)
 }
+
+func epwingExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	if options.SplitSubbooks && options.MergeSubbooks {
+		return errors.New("merging subbooks cannot be combined with splitting them")
+	}
+
+	book, err := zig.Load(inputPath)
+	if err != nil {
+		return err
+	}
+
+	indices, err := epwingSelectSubbooks(book, options.Subbooks)
+	if err != nil {
+		return err
+	}
+
+	epwingExtractors := makeEpwingExtractors()
+
+	var (
+		subbooks       []epwingSubbookData
+		subbookIndices []int
+		sequence       int
+	)
+
+	for _, index := range indices {
+		subbook := book.Subbooks[index]
+		if extractor, ok := epwingExtractors[subbook.Title]; ok {
+			subbooks = append(subbooks, epwingExtractSubbook(subbook, extractor, &sequence))
+			subbookIndices = append(subbookIndices, index)
+		} else if options.SkipUnsupported {
+			fmt.Printf("Skipping subbook '%s': no compatible extractor\n", subbook.Title)
+		} else {
+			return fmt.Errorf("failed to find compatible extractor for '%s'", subbook.Title)
+		}
+	}
+
+	if len(subbooks) == 0 {
+		return errors.New("no compatible subbooks to convert")
+	}
+
+	if !options.SplitSubbooks {
+		return epwingWriteDb(outputPath, title, subbooks, options.MergeSubbooks, stride, pretty)
+	}
+
+	ext := filepath.Ext(outputPath)
+	for i, subbook := range subbooks {
+		subbookTitle := subbook.title
+		if title != "" {
+			subbookTitle = title + " (" + subbook.title + ")"
+		}
+
+		subbookPath := fmt.Sprintf("%s_%d%s", strings.TrimSuffix(outputPath, ext), subbookIndices[i]+1, ext)
+		if err := epwingWriteDb(subbookPath, subbookTitle, []epwingSubbookData{subbook}, false, stride, pretty); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -2,20 +2,84 @@ package yomichan
 
 import (
 	"bufio"
+	"fmt"
 	"os"
 	"strconv"
 	"strings"
 )
 
-func frequencyTermsExportDb(inputPath, outputPath, language, title string, stride int, pretty bool) error {
-	return frequencyExportDb(inputPath, outputPath, language, title, stride, pretty, "term_meta")
+type frequencyValue struct {
+	Value        int    `json:"value"`
+	DisplayValue string `json:"displayValue"`
 }
 
-func frequencyKanjiExportDb(inputPath, outputPath, language, title string, stride int, pretty bool) error {
-	return frequencyExportDb(inputPath, outputPath, language, title, stride, pretty, "kanji_meta")
+type frequencyReadingData struct {
+	Reading   string `json:"reading"`
+	Frequency any    `json:"frequency"`
 }
 
-func frequencyExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, key string) error {
+// Builds the data of a frequency record. A plain count is kept when
+// there is neither a reading nor a display value, as before.
+func makeFrequencyData(count int, reading, display string) any {
+	var frequency any = count
+	if display != "" {
+		frequency = frequencyValue{Value: count, DisplayValue: display}
+	}
+	if reading != "" {
+		return frequencyReadingData{Reading: reading, Frequency: frequency}
+	}
+	return frequency
+}
+
+// Splits the optional columns following the expression and count into
+// a reading (for terms only) and a string to display instead of the
+// count. Lists with a single extra column use it for either, so it is
+// only taken as a reading when written in kana; otherwise, as with a
+// malformed reading followed by a display value, it is left out.
+func frequencyExtraColumns(extra []string, terms bool) (reading, display string) {
+	for i := range extra {
+		extra[i] = strings.TrimSpace(extra[i])
+	}
+
+	if terms && len(extra) > 0 {
+		if isKanaOnly(extra[0]) {
+			reading, extra = extra[0], extra[1:]
+		} else if len(extra) > 1 {
+			extra = extra[1:]
+		}
+	}
+	if len(extra) > 0 {
+		display = extra[0]
+	}
+
+	return reading, display
+}
+
+// Converts the frequency mode option into the name used by the index,
+// which tells Yomichan whether lower values (ranks) or higher values
+// (occurrence counts) are more frequent.
+func frequencyModeName(mode string) (string, error) {
+	switch strings.ToLower(mode) {
+	case "":
+		return "", nil
+	case "rank", "rank-based":
+		return "rank-based", nil
+	case "occurrence", "occurrence-based":
+		return "occurrence-based", nil
+	default:
+		return "", fmt.Errorf("unsupported frequency mode: %s", mode)
+	}
+}
+
+func frequencyTermsExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	return frequencyExportDb(inputPath, outputPath, language, title, stride, pretty, options, "term_meta")
+}
+
+func frequencyKanjiExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	return frequencyExportDb(inputPath, outputPath, language, title, stride, pretty, options, "kanji_meta")
+}
+
+func frequencyExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions, key string) error {
 	reader, err := os.Open(inputPath)
 	if err != nil {
 		return err
@@ -44,7 +108,8 @@ func frequencyExportDb(inputPath, outputPath, language, title string, stride int
 			}
 		}
 
-		frequencies = append(frequencies, dbMeta{expression, "freq", count})
+		reading, display := frequencyExtraColumns(parts[2:], key == "term_meta")
+		frequencies = append(frequencies, dbMeta{expression, "freq", makeFrequencyData(count, reading, display)})
 	}
 
 	if title == "" {
@@ -55,10 +120,16 @@ func frequencyExportDb(inputPath, outputPath, language, title string, stride int
 		key: frequencies.crush(),
 	}
 
+	frequencyMode, err := frequencyModeName(options.FrequencyMode)
+	if err != nil {
+		return err
+	}
+
 	index := dbIndex{
-		Title:     title,
-		Revision:  "frequency1",
-		Sequenced: false,
+		Title:         title,
+		Revision:      "frequency1",
+		Sequenced:     false,
+		FrequencyMode: frequencyMode,
 	}
 
 	return writeDb(
@@ -0,0 +1,191 @@
+package yomichan
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Limits the length of the headwords tried at each position, since
+// longer ones are rare and only slow down the segmentation.
+const corpusMaxHeadwordLength = 16
+
+type corpusLexicon struct {
+	headwords map[string]bool
+	maxLength int
+}
+
+// Builds the segmentation lexicon from the kanji and reading forms of a
+// JMdict file.
+func loadCorpusLexicon(path string) (corpusLexicon, error) {
+	lexicon := corpusLexicon{headwords: make(map[string]bool)}
+
+	dictionary, _, _, err := loadJmdictFile(path)
+	if err != nil {
+		return lexicon, err
+	}
+
+	add := func(headword string) {
+		length := utf8.RuneCountInString(headword)
+		if length == 0 || length > corpusMaxHeadwordLength {
+			return
+		}
+		lexicon.headwords[headword] = true
+		if length > lexicon.maxLength {
+			lexicon.maxLength = length
+		}
+	}
+
+	for _, entry := range dictionary.Entries {
+		for _, kanji := range entry.Kanji {
+			add(kanji.Expression)
+		}
+		for _, reading := range entry.Readings {
+			add(reading.Reading)
+		}
+	}
+
+	return lexicon, nil
+}
+
+// Splits text into headwords by taking the longest headword found in
+// the lexicon at each position. Characters which do not start any
+// headword are skipped. Inflected forms are not deconjugated, so they
+// are counted as their longest matching prefix.
+func (lexicon corpusLexicon) segment(text []rune, visit func(headword string)) {
+	for position := 0; position < len(text); {
+		length := lexicon.maxLength
+		if remaining := len(text) - position; length > remaining {
+			length = remaining
+		}
+
+		for ; length > 0; length-- {
+			if headword := string(text[position : position+length]); lexicon.headwords[headword] {
+				visit(headword)
+				break
+			}
+		}
+
+		if length == 0 {
+			length = 1
+		}
+		position += length
+	}
+}
+
+// Converts occurrence counts into rank-based frequency records, with
+// the most frequent item ranked first. Items seen fewer than minCount
+// times are left out.
+func corpusFrequencyRanks(counts map[string]int, minCount int) dbMetaList {
+	var items []string
+	for item, count := range counts {
+		if count >= minCount {
+			items = append(items, item)
+		}
+	}
+
+	sort.Slice(items, func(i, j int) bool {
+		if counts[items[i]] != counts[items[j]] {
+			return counts[items[i]] > counts[items[j]]
+		}
+		return items[i] < items[j]
+	})
+
+	var frequencies dbMetaList
+	for i, item := range items {
+		frequencies = append(frequencies, dbMeta{item, "freq", i + 1})
+	}
+
+	return frequencies
+}
+
+func corpusFrequencyExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	var (
+		lexicon     corpusLexicon
+		kanjiCounts = make(map[string]int)
+		termCounts  = make(map[string]int)
+	)
+
+	if options.Lexicon != "" {
+		var err error
+		if lexicon, err = loadCorpusLexicon(options.Lexicon); err != nil {
+			return err
+		}
+	} else {
+		fmt.Println("No lexicon given, only counting kanji")
+	}
+
+	minCount := options.MinCount
+	if minCount < 1 {
+		minCount = 1
+	}
+
+	err := filepath.WalkDir(inputPath, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+
+		if !utf8.Valid(data) {
+			fmt.Printf("Skipping file which is not UTF-8 encoded: %s\n", path)
+			return nil
+		}
+
+		text := []rune(string(data))
+		for _, char := range text {
+			if unicode.Is(unicode.Han, char) {
+				kanjiCounts[string(char)]++
+			}
+		}
+
+		if lexicon.headwords != nil {
+			lexicon.segment(text, func(headword string) {
+				termCounts[headword]++
+			})
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	if title == "" {
+		title = "Corpus Frequency"
+	}
+
+	recordData := map[string]dbRecordList{
+		"kanji_meta": corpusFrequencyRanks(kanjiCounts, minCount).crush(),
+	}
+
+	if lexicon.headwords != nil {
+		recordData["term_meta"] = corpusFrequencyRanks(termCounts, minCount).crush()
+	}
+
+	index := dbIndex{
+		Title:         title,
+		Revision:      "corpusfreq1",
+		Sequenced:     false,
+		FrequencyMode: "rank-based",
+	}
+
+	return writeDb(
+		outputPath,
+		index,
+		recordData,
+		stride,
+		pretty,
+	)
+}
@@ -0,0 +1,57 @@
+package yomichan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCorpusLexiconSegment(t *testing.T) {
+	lexicon := corpusLexicon{headwords: make(map[string]bool)}
+	for _, headword := range []string{"日本", "日本語", "語", "を", "勉強", "勉強する", "する", "本"} {
+		lexicon.headwords[headword] = true
+		if length := len([]rune(headword)); length > lexicon.maxLength {
+			lexicon.maxLength = length
+		}
+	}
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"日本語を勉強する", []string{"日本語", "を", "勉強する"}},
+		{"日本の本", []string{"日本", "本"}},
+		{"勉強した", []string{"勉強"}},
+		{"「日本」", []string{"日本"}},
+		{"日", nil},
+		{"", nil},
+	}
+
+	for _, test := range tests {
+		var got []string
+		lexicon.segment([]rune(test.text), func(headword string) {
+			got = append(got, headword)
+		})
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("segment(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+}
+
+func TestCorpusFrequencyRanks(t *testing.T) {
+	counts := map[string]int{"の": 10, "日本": 4, "本": 4, "猫": 7, "犬": 1}
+
+	tests := []struct {
+		minCount int
+		want     dbMetaList
+	}{
+		{1, dbMetaList{{"の", "freq", 1}, {"猫", "freq", 2}, {"日本", "freq", 3}, {"本", "freq", 4}, {"犬", "freq", 5}}},
+		{4, dbMetaList{{"の", "freq", 1}, {"猫", "freq", 2}, {"日本", "freq", 3}, {"本", "freq", 4}}},
+		{11, nil},
+	}
+
+	for _, test := range tests {
+		if got := corpusFrequencyRanks(counts, test.minCount); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("corpusFrequencyRanks(%v) = %v, want %v", test.minCount, got, test.want)
+		}
+	}
+}
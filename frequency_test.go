@@ -0,0 +1,31 @@
+package yomichan
+
+import "testing"
+
+func TestFrequencyExtraColumns(t *testing.T) {
+	tests := []struct {
+		extra   []string
+		terms   bool
+		reading string
+		display string
+	}{
+		{nil, true, "", ""},
+		{[]string{"にほん"}, true, "にほん", ""},
+		{[]string{" ニッポン "}, true, "ニッポン", ""},
+		{[]string{"にっぽん", "1.2万"}, true, "にっぽん", "1.2万"},
+		{[]string{"1.2万"}, true, "", "1.2万"},
+		{[]string{"top 100"}, true, "", "top 100"},
+		{[]string{"", "1.2万"}, true, "", "1.2万"},
+		{[]string{"nihon", "1.2万"}, true, "", "1.2万"},
+		{[]string{"日本", ""}, true, "", ""},
+		{[]string{"にほん"}, false, "", "にほん"},
+		{[]string{"1.2万", "extra"}, false, "", "1.2万"},
+	}
+
+	for _, test := range tests {
+		reading, display := frequencyExtraColumns(test.extra, test.terms)
+		if reading != test.reading || display != test.display {
+			t.Errorf("frequencyExtraColumns(%q, %v) = %q, %q, want %q, %q", test.extra, test.terms, reading, display, test.reading, test.display)
+		}
+	}
+}
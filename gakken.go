@@ -12,8 +12,6 @@ type gakkenExtractor struct {
 	readGroupExp *regexp.Regexp
 	expVarExp    *regexp.Regexp
 	metaExp      *regexp.Regexp
-	v5Exp        *regexp.Regexp
-	v1Exp        *regexp.Regexp
 }
 
 func makeGakkenExtractor() epwingExtractor {
@@ -22,8 +20,6 @@ func makeGakkenExtractor() epwingExtractor {
 		readGroupExp: regexp.MustCompile(`[‐・]+`),
 		expVarExp:    regexp.MustCompile(`\(([^\)]*)\)`),
 		metaExp:      regexp.MustCompile(`（([^）]*)）`),
-		v5Exp:        regexp.MustCompile(`(動.[四五](［[^］]+］)?)|(動..二)`),
-		v1Exp:        regexp.MustCompile(`(動..一)`),
 	}
 }
 
@@ -124,26 +120,23 @@ func (*gakkenExtractor) extractKanji(entry zig.BookEntry) []dbKanji {
 	return nil
 }
 
+func (*gakkenExtractor) extractTermMeta(entry zig.BookEntry, terms []dbTerm) []dbMeta {
+	return nil
+}
+
 func (e *gakkenExtractor) exportRules(term *dbTerm, tags []string) {
-	for _, tag := range tags {
-		if tag == "形" {
-			term.addRules("adj-i")
-		} else if tag == "動サ変" && (strings.HasSuffix(term.Expression, "する") || strings.HasSuffix(term.Expression, "為る")) {
-			term.addRules("vs")
-		} else if term.Expression == "来る" {
-			term.addRules("vk")
-		} else if e.v5Exp.MatchString(tag) {
-			term.addRules("v5")
-		} else if e.v1Exp.MatchString(tag) {
-			term.addRules("v1")
-		}
-	}
+	term.addRules(japaneseGrammarRules(term.Expression, tags)...)
+	term.addDefinitionTags(japanesePartsOfSpeech(tags)...)
 }
 
 func (*gakkenExtractor) getRevision() string {
 	return "gakken"
 }
 
+func (*gakkenExtractor) getTags() dbTagList {
+	return japaneseGrammarTags()
+}
+
 func (*gakkenExtractor) getFontNarrow() map[int]string {
 	return map[int]string{
 		41550: "ī",
@@ -0,0 +1,179 @@
+package yomichan
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	zig "foosoft.net/projects/zero-epwing-go"
+)
+
+type gakkenKanjiExtractor struct {
+	*gakkenExtractor
+	kanjiExp     *regexp.Regexp
+	onExp        *regexp.Regexp
+	onLineExp    *regexp.Regexp
+	kunLineExp   *regexp.Regexp
+	kunExp       *regexp.Regexp
+	katakanaExp  *regexp.Regexp
+	strokesExp   *regexp.Regexp
+	radicalExp   *regexp.Regexp
+	meaningExp   *regexp.Regexp
+	formationExp *regexp.Regexp
+	jisExp       *regexp.Regexp
+	ucsExp       *regexp.Regexp
+	indexExp     *regexp.Regexp
+}
+
+func makeGakkenKanjiExtractor() epwingExtractor {
+	origins := `\((?:漢|呉|唐|宋|慣)\)`
+	return &gakkenKanjiExtractor{
+		gakkenExtractor: makeGakkenExtractor().(*gakkenExtractor),
+		kanjiExp:        regexp.MustCompile(`^[^\p{Han}]*(\p{Han})[^\p{Han}]*$`),
+		onExp:           regexp.MustCompile(`([\p{Katakana}ー]+)\s*` + origins + `|` + origins + `\s*([\p{Katakana}ー]+)`),
+		onLineExp:       regexp.MustCompile(`^[〔［【]?音(?:読み)?[〕］】]?[\s　:：]*(.+)$`),
+		kunLineExp:      regexp.MustCompile(`^[〔［【]?訓(?:読み)?[〕］】]?[\s　:：]*(.+)$`),
+		kunExp:          regexp.MustCompile(`[‐\-．.]?\p{Hiragana}[\p{Hiragana}‐\-．.]*`),
+		katakanaExp:     regexp.MustCompile(`[\p{Katakana}ー]+`),
+		strokesExp:      regexp.MustCompile(`(?:総画数?|画数)[〕］】]?[\s　:：]*([0-9０-９]+)|([0-9０-９]+)画`),
+		radicalExp:      regexp.MustCompile(`部首[〕］】]?[\s　:：]*(\([^)]+\)|\p{Han})`),
+		meaningExp:      regexp.MustCompile(`^(?:[①-⑳❶-❿⓫-⓴㊀-㊉]|【意味】)\s*(.+)$`),
+		formationExp:    regexp.MustCompile(`象形|指事|会意|形声|仮借|転注`),
+		jisExp:          regexp.MustCompile(`JIS[\s　:：]*([0-9A-Fa-f]{4})`),
+		ucsExp:          regexp.MustCompile(`(?:Unicode|UCS|U\+)[\s　:：]*([0-9A-Fa-f]{4,5})`),
+		indexExp:        regexp.MustCompile(`学研[\s　:：]*([0-9０-９]+)`),
+	}
+}
+
+func (e *gakkenKanjiExtractor) extractTerms(entry zig.BookEntry, sequence int) []dbTerm {
+	// single character headings are exported as kanji instead
+	if e.kanjiExp.MatchString(entry.Heading) {
+		return nil
+	}
+
+	return e.gakkenExtractor.extractTerms(entry, sequence)
+}
+
+func (e *gakkenKanjiExtractor) extractKanji(entry zig.BookEntry) []dbKanji {
+	matches := e.kanjiExp.FindStringSubmatch(entry.Heading)
+	if matches == nil {
+		return nil
+	}
+
+	kanji := dbKanji{
+		Character: matches[1],
+		Stats:     make(map[string]string),
+	}
+
+	entryText := cosmetics.Replace(entry.Text)
+
+	for _, line := range strings.Split(entryText, "\n") {
+		line = strings.TrimSpace(line)
+
+		for _, onMatches := range e.onExp.FindAllStringSubmatch(line, -1) {
+			kanji.Onyomi = appendStringUnique(kanji.Onyomi, onMatches[1]+onMatches[2])
+		}
+
+		if onMatches := e.onLineExp.FindStringSubmatch(line); onMatches != nil {
+			for _, reading := range e.katakanaExp.FindAllString(onMatches[1], -1) {
+				kanji.Onyomi = appendStringUnique(kanji.Onyomi, reading)
+			}
+		}
+
+		if kunMatches := e.kunLineExp.FindStringSubmatch(line); kunMatches != nil {
+			for _, reading := range e.kunExp.FindAllString(kunMatches[1], -1) {
+				reading = strings.NewReplacer("‐", ".", "-", ".", "．", ".").Replace(reading)
+				kanji.Kunyomi = appendStringUnique(kanji.Kunyomi, reading)
+			}
+		}
+
+		if meaningMatches := e.meaningExp.FindStringSubmatch(line); meaningMatches != nil {
+			kanji.Meanings = append(kanji.Meanings, meaningMatches[1])
+		}
+
+		if _, ok := kanji.Stats["strokes"]; !ok {
+			if strokeMatches := e.strokesExp.FindStringSubmatch(line); strokeMatches != nil {
+				kanji.Stats["strokes"] = normalizeDigits(strokeMatches[1] + strokeMatches[2])
+			}
+		}
+
+		if _, ok := kanji.Stats["radical"]; !ok {
+			if radicalMatches := e.radicalExp.FindStringSubmatch(line); radicalMatches != nil {
+				kanji.Stats["radical"] = strings.Trim(radicalMatches[1], "()")
+			}
+		}
+
+		if _, ok := kanji.Stats["formation"]; !ok {
+			if formation := e.formationExp.FindString(line); formation != "" {
+				kanji.Stats["formation"] = formation
+			}
+		}
+
+		if jisMatches := e.jisExp.FindStringSubmatch(line); jisMatches != nil {
+			kanji.Stats["jis208"] = jisToKuten(jisMatches[1])
+		}
+
+		if ucsMatches := e.ucsExp.FindStringSubmatch(line); ucsMatches != nil {
+			kanji.Stats["ucs"] = strings.ToLower(ucsMatches[1])
+		}
+
+		if indexMatches := e.indexExp.FindStringSubmatch(line); indexMatches != nil {
+			kanji.Stats["gakken"] = normalizeDigits(indexMatches[1])
+		}
+
+		if strings.Contains(line, "常用漢字") {
+			kanji.addTags("jouyou")
+		} else if strings.Contains(line, "人名用漢字") {
+			kanji.addTags("jinmeiyou")
+		}
+	}
+
+	if len(kanji.Meanings) == 0 && len(kanji.Onyomi) == 0 && len(kanji.Kunyomi) == 0 {
+		return nil
+	}
+
+	return []dbKanji{kanji}
+}
+
+func (e *gakkenKanjiExtractor) getTags() dbTagList {
+	return append(e.gakkenExtractor.getTags(),
+		dbTag{Name: "jouyou", Notes: "included in list of regular-use characters", Category: "frequent", Order: -5},
+		dbTag{Name: "jinmeiyou", Notes: "included in list of characters for use in personal names", Category: "frequent", Order: -5},
+
+		dbTag{Name: "strokes", Notes: "Stroke count", Category: "misc"},
+		dbTag{Name: "radical", Notes: "Radical", Category: "misc"},
+
+		dbTag{Name: "jis208", Notes: "JIS X 0208-1997 kuten code", Category: "code"},
+		dbTag{Name: "ucs", Notes: "Unicode hex code", Category: "code"},
+
+		dbTag{Name: "formation", Notes: "Character formation (六書)", Category: "class"},
+
+		dbTag{Name: "gakken", Notes: "学研漢和大字典", Category: "index"},
+	)
+}
+
+// Converts fullwidth digits into their ASCII equivalents.
+func normalizeDigits(text string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= '０' && r <= '９' {
+			return r - '０' + '0'
+		}
+		return r
+	}, text)
+}
+
+// Converts a hexadecimal JIS code (e.g. "3021") into the plane, row
+// and cell notation used by KANJIDIC2 (e.g. "1-16-01").
+func jisToKuten(code string) string {
+	value, err := strconv.ParseUint(code, 16, 16)
+	if err != nil {
+		return code
+	}
+	ku := int(value>>8) - 0x20
+	ten := int(value&0xff) - 0x20
+	if ku < 1 || ku > 94 || ten < 1 || ten > 94 {
+		return code
+	}
+	return fmt.Sprintf("1-%02d-%02d", ku, ten)
+}
@@ -0,0 +1,60 @@
+package yomichan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJisToKuten(t *testing.T) {
+	tests := []struct {
+		code string
+		want string
+	}{
+		{"3021", "1-16-01"},
+		{"4F53", "1-47-51"},
+		{"7426", "1-84-06"},
+		{"2121", "1-01-01"},
+		{"2020", "2020"},
+		{"7F7F", "7F7F"},
+		{"xyz", "xyz"},
+	}
+
+	for _, test := range tests {
+		if got := jisToKuten(test.code); got != test.want {
+			t.Errorf("jisToKuten(%q) = %q, want %q", test.code, got, test.want)
+		}
+	}
+}
+
+func TestGakkenKanjiReadings(t *testing.T) {
+	extractor := makeGakkenKanjiExtractor().(*gakkenKanjiExtractor)
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{"あたら‐しい・あら‐た", []string{"あたら‐しい", "あら‐た"}},
+		{"にい． ‐ぎ", []string{"にい．", "‐ぎ"}},
+		{"． ‐ ・ -", nil},
+	}
+
+	for _, test := range tests {
+		if got := extractor.kunExp.FindAllString(test.text, -1); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("kunExp.FindAllString(%q) = %q, want %q", test.text, got, test.want)
+		}
+	}
+
+	names := make(map[string]bool)
+	for _, tag := range extractor.getTags() {
+		names[tag.Name] = true
+	}
+	wanted := []string{"jouyou", "jis208", "gakken"}
+	for _, tag := range extractor.gakkenExtractor.getTags() {
+		wanted = append(wanted, tag.Name)
+	}
+	for _, name := range wanted {
+		if !names[name] {
+			t.Errorf("getTags() is missing %q", name)
+		}
+	}
+}
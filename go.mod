@@ -8,6 +8,5 @@ require (
 	github.com/andlabs/ui v0.0.0-20200610043537-70a69d6ae31e
 	github.com/mattn/go-sqlite3 v1.14.14
 	golang.org/x/exp v0.0.0-20221207211629-99ab8fa1c11f
+	golang.org/x/text v0.3.7
 )
-
-require golang.org/x/text v0.3.7 // indirect
@@ -0,0 +1,362 @@
+package yomichan
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Tag added to transcriptions derived from kana readings.
+const ipaDerivedTag = "Tokyo"
+
+// Broad transcriptions of the kana segments produced by
+// makeKanaSegments, following the standard Tokyo pronunciation:
+// unrounded ɯ for う, alveolo-palatal ɕ/tɕ/dʑ for し/ち/じ, palatal ç
+// and bilabial ɸ for ひ/ふ, the flap ɾ for the r-row and ɰ for わ.
+// Palatalized consonants are marked with ʲ. Voiced ざ/ず/ぜ/ぞ are
+// given as affricates, which is how they are pronounced at the start
+// of a word.
+var ipaKanaSegments = map[string]string{
+	"あ": "a", "い": "i", "う": "ɯ", "え": "e", "お": "o",
+	"ぁ": "a", "ぃ": "i", "ぅ": "ɯ", "ぇ": "e", "ぉ": "o",
+	"か": "ka", "き": "kʲi", "く": "kɯ", "け": "ke", "こ": "ko",
+	"が": "ɡa", "ぎ": "ɡʲi", "ぐ": "ɡɯ", "げ": "ɡe", "ご": "ɡo",
+	"さ": "sa", "し": "ɕi", "す": "sɯ", "せ": "se", "そ": "so",
+	"ざ": "dza", "じ": "dʑi", "ず": "dzɯ", "ぜ": "dze", "ぞ": "dzo",
+	"た": "ta", "ち": "tɕi", "つ": "tsɯ", "て": "te", "と": "to",
+	"だ": "da", "ぢ": "dʑi", "づ": "dzɯ", "で": "de", "ど": "do",
+	"な": "na", "に": "ɲi", "ぬ": "nɯ", "ね": "ne", "の": "no",
+	"は": "ha", "ひ": "çi", "ふ": "ɸɯ", "へ": "he", "ほ": "ho",
+	"ば": "ba", "び": "bʲi", "ぶ": "bɯ", "べ": "be", "ぼ": "bo",
+	"ぱ": "pa", "ぴ": "pʲi", "ぷ": "pɯ", "ぺ": "pe", "ぽ": "po",
+	"ま": "ma", "み": "mʲi", "む": "mɯ", "め": "me", "も": "mo",
+	"や": "ja", "ゆ": "jɯ", "よ": "jo",
+	"ゃ": "ja", "ゅ": "jɯ", "ょ": "jo",
+	"ら": "ɾa", "り": "ɾʲi", "る": "ɾɯ", "れ": "ɾe", "ろ": "ɾo",
+	"わ": "ɰa", "ゎ": "ɰa", "ゐ": "i", "ゑ": "e", "を": "o",
+	"ゔ":  "bɯ",
+	"きゃ": "kʲa", "きゅ": "kʲɯ", "きょ": "kʲo",
+	"ぎゃ": "ɡʲa", "ぎゅ": "ɡʲɯ", "ぎょ": "ɡʲo",
+	"しゃ": "ɕa", "しゅ": "ɕɯ", "しょ": "ɕo", "しぇ": "ɕe",
+	"じゃ": "dʑa", "じゅ": "dʑɯ", "じょ": "dʑo", "じぇ": "dʑe",
+	"ちゃ": "tɕa", "ちゅ": "tɕɯ", "ちょ": "tɕo", "ちぇ": "tɕe", "ちぁ": "tɕa",
+	"ぢゃ": "dʑa", "ぢゅ": "dʑɯ", "ぢょ": "dʑo",
+	"つぁ": "tsa", "つぇ": "tse",
+	"てぃ": "tʲi", "でぃ": "dʲi", "でゅ": "dʲɯ",
+	"にゃ": "ɲa", "にゅ": "ɲɯ", "にょ": "ɲo",
+	"ひゃ": "ça", "ひゅ": "çɯ", "ひょ": "ço",
+	"びゃ": "bʲa", "びゅ": "bʲɯ", "びょ": "bʲo",
+	"ぴゃ": "pʲa", "ぴゅ": "pʲɯ", "ぴょ": "pʲo",
+	"ふぁ": "ɸa", "ふぃ": "ɸʲi", "ふぇ": "ɸe", "ふぉ": "ɸo",
+	"みゃ": "mʲa", "みゅ": "mʲɯ", "みょ": "mʲo",
+	"りゃ": "ɾʲa", "りゅ": "ɾʲɯ", "りょ": "ɾʲo",
+	"うぁ": "ɰa", "うぃ": "ɰi", "うぇ": "ɰe", "うぉ": "ɰo",
+	"くゎ": "kɰa", "くゅ": "kʲɯ",
+	"ゔぁ": "ba", "ゔぃ": "bʲi", "ゔぇ": "be", "ゔぉ": "bo", "ゔゅ": "bʲɯ",
+	"とぅ": "tɯ", "どぅ": "dɯ", "いぇ": "je",
+}
+
+// Vowels which lengthen the preceding mora when it ends in one of the
+// given vowels: repeated vowels, う after o (おう) and い after e (えい).
+var ipaLongVowels = map[string]string{
+	"あ": "a",
+	"い": "ie",
+	"う": "ɯo",
+	"え": "e",
+	"お": "o",
+}
+
+// Joins segments which makeKanaSegments leaves apart but which are
+// pronounced as a single mora, such as ゔ and ぁ in ゔぁ.
+func ipaJoinSegments(segments []string) []string {
+	var joined []string
+	for i := 0; i < len(segments); i++ {
+		if i+1 < len(segments) {
+			if _, ok := ipaKanaSegments[segments[i]+segments[i+1]]; ok {
+				joined = append(joined, segments[i]+segments[i+1])
+				i++
+				continue
+			}
+		}
+		joined = append(joined, segments[i])
+	}
+	return joined
+}
+
+// Returns the nasal written as ん before the given transcription,
+// which assimilates to the place of articulation of the following
+// consonant and is uvular elsewhere.
+func ipaMoraicNasal(next string) string {
+	switch {
+	case strings.HasPrefix(next, "p"), strings.HasPrefix(next, "b"), strings.HasPrefix(next, "m"):
+		return "m"
+	case strings.HasPrefix(next, "tɕ"), strings.HasPrefix(next, "dʑ"), strings.HasPrefix(next, "ɲ"):
+		return "ɲ"
+	case strings.HasPrefix(next, "t"), strings.HasPrefix(next, "d"), strings.HasPrefix(next, "n"), strings.HasPrefix(next, "ɾ"):
+		return "n"
+	case strings.HasPrefix(next, "k"), strings.HasPrefix(next, "ɡ"):
+		return "ŋ"
+	default:
+		return "ɴ"
+	}
+}
+
+// Returns the consonant written as っ before the given transcription,
+// which doubles the first consonant of the following mora (or the stop
+// of an affricate). A glottal stop is used at the end of a word.
+func ipaGeminate(next string) string {
+	for _, prefix := range []string{"tɕ", "ts", "dʑ", "dz"} {
+		if strings.HasPrefix(next, prefix) {
+			return prefix[:1]
+		}
+	}
+	for _, char := range next {
+		if !strings.ContainsRune("aiɯeo", char) {
+			return string(char)
+		}
+		break
+	}
+	return "ʔ"
+}
+
+// Derives a broad IPA transcription from a kana reading using the
+// Tokyo Japanese mapping above. The long vowel mark ー lengthens the
+// preceding vowel, as do the vowel sequences in ipaLongVowels, so that
+// きょう is [kʲoː]. The reading is taken to be a single word, so a
+// verb such as おもう is also lengthened. Pitch accent is not
+// represented. Returns false if the reading contains characters which
+// are not kana.
+func kanaToIpa(reading string) (string, bool) {
+	segments := ipaJoinSegments(makeKanaSegments(reading))
+	if len(segments) == 0 {
+		return "", false
+	}
+
+	transcriptions := make([]string, len(segments))
+	for i, segment := range segments {
+		transcriptions[i] = ipaKanaSegments[segment]
+	}
+
+	var builder strings.Builder
+	for i, segment := range segments {
+		next := ""
+		if i+1 < len(segments) {
+			next = transcriptions[i+1]
+		}
+
+		switch segment {
+		case "ん":
+			builder.WriteString(ipaMoraicNasal(next))
+		case "っ":
+			builder.WriteString(ipaGeminate(next))
+		case "ー":
+			if builder.Len() > 0 {
+				builder.WriteString("ː")
+			}
+		default:
+			if vowels, ok := ipaLongVowels[segment]; ok && builder.Len() > 0 {
+				last, _ := utf8.DecodeLastRuneInString(builder.String())
+				if strings.ContainsRune(vowels, last) {
+					builder.WriteString("ː")
+					continue
+				}
+			}
+			builder.WriteString(transcriptions[i])
+		}
+	}
+
+	return "[" + builder.String() + "]", true
+}
+
+type ipaRecords struct {
+	metas   dbMetaList
+	indices map[[2]string]int
+}
+
+// Adds transcriptions to the record of a headword, merging them with
+// any transcriptions already found for it.
+func (records *ipaRecords) add(expression, reading string, transcriptions ...phoneticTranscription) {
+	if records.indices == nil {
+		records.indices = make(map[[2]string]int)
+	}
+
+	key := [2]string{expression, reading}
+	index, ok := records.indices[key]
+	if !ok {
+		index = len(records.metas)
+		records.indices[key] = index
+		records.metas = append(records.metas, dbMeta{expression, "ipa", phoneticData{Reading: reading}})
+	}
+
+	data := records.metas[index].Data.(phoneticData)
+	for _, transcription := range transcriptions {
+		found := false
+		for _, existing := range data.Transcriptions {
+			if existing.Ipa == transcription.Ipa {
+				found = true
+				break
+			}
+		}
+		if !found {
+			data.Transcriptions = append(data.Transcriptions, transcription)
+		}
+	}
+	records.metas[index].Data = data
+}
+
+// Reads tab-separated lines containing the expression, the reading,
+// comma-separated transcriptions and space-separated tags. When the
+// transcription column is missing or empty, one is derived from the
+// reading.
+func ipaLoadTsv(reader io.Reader, records *ipaRecords) error {
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimPrefix(scanner.Text(), "\uFEFF")
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		parts := strings.Split(line, "\t")
+		for len(parts) < 4 {
+			parts = append(parts, "")
+		}
+
+		expression := strings.TrimSpace(parts[0])
+		reading := strings.TrimSpace(parts[1])
+		if reading == "" {
+			reading = expression
+		}
+		tags := strings.Fields(parts[3])
+
+		var transcriptions []phoneticTranscription
+		for _, ipa := range strings.Split(parts[2], ",") {
+			if ipa = strings.TrimSpace(ipa); ipa != "" {
+				transcriptions = append(transcriptions, phoneticTranscription{Ipa: ipa, Tags: tags})
+			}
+		}
+
+		if len(transcriptions) == 0 {
+			ipa, ok := kanaToIpa(reading)
+			if !ok {
+				fmt.Printf("Skipping line %d without a transcription or kana reading\n", lineNumber)
+				continue
+			}
+			transcriptions = append(transcriptions, phoneticTranscription{Ipa: ipa, Tags: append(tags, ipaDerivedTag)})
+		}
+
+		records.add(expression, reading, transcriptions...)
+	}
+
+	return scanner.Err()
+}
+
+// Reads the pronunciations of Wiktextract JSONL entries in any
+// language. Japanese entries without a transcription get one derived
+// from their kana reading.
+func ipaLoadWiktionary(reader io.Reader, records *ipaRecords) error {
+	bufferedReader := bufio.NewReader(reader)
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := bufferedReader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var entry wiktionaryEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return fmt.Errorf("failed to parse line %d: %w", lineNumber, err)
+			}
+
+			headwords := [][2]string{{entry.Word, ""}}
+			if entry.LangCode == "" || entry.LangCode == "ja" {
+				headwords = wiktionaryHeadwords(entry)
+			}
+
+			for _, meta := range wiktionaryExtractTermMeta(entry, headwords) {
+				if data, ok := meta.Data.(phoneticData); ok {
+					records.add(meta.Expression, data.Reading, data.Transcriptions...)
+				}
+			}
+
+			if entry.LangCode == "" || entry.LangCode == "ja" {
+				for _, headword := range headwords {
+					expression, reading := headword[0], headword[1]
+					if reading == "" {
+						reading = expression
+					}
+					if _, ok := records.indices[[2]string{expression, reading}]; ok {
+						continue
+					}
+					if ipa, ok := kanaToIpa(reading); ok {
+						records.add(expression, reading, phoneticTranscription{Ipa: ipa, Tags: []string{ipaDerivedTag}})
+					}
+				}
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	return nil
+}
+
+func ipaExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	fp, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	var records ipaRecords
+	if strings.ToLower(filepath.Ext(inputPath)) == ".jsonl" {
+		err = ipaLoadWiktionary(fp, &records)
+	} else {
+		err = ipaLoadTsv(fp, &records)
+	}
+	if err != nil {
+		return err
+	}
+
+	var tagNames []string
+	for _, meta := range records.metas {
+		for _, transcription := range meta.Data.(phoneticData).Transcriptions {
+			tagNames = appendStringUnique(tagNames, transcription.Tags...)
+		}
+	}
+
+	var tags dbTagList
+	for _, name := range tagNames {
+		tags = append(tags, dbTag{Name: name})
+	}
+
+	if title == "" {
+		title = "IPA"
+	}
+
+	recordData := map[string]dbRecordList{
+		"term_meta": records.metas.crush(),
+		"tag":       tags.crush(),
+	}
+
+	index := dbIndex{
+		Title:     title,
+		Revision:  "ipa1",
+		Sequenced: false,
+	}
+
+	return writeDb(
+		outputPath,
+		index,
+		recordData,
+		stride,
+		pretty,
+	)
+}
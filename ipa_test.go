@@ -0,0 +1,67 @@
+package yomichan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestKanaToIpa(t *testing.T) {
+	tests := []struct {
+		reading string
+		want    string
+		ok      bool
+	}{
+		{"さくら", "[sakɯɾa]", true},
+		{"しんぶん", "[ɕimbɯɴ]", true},
+		{"さんねん", "[sanneɴ]", true},
+		{"りんご", "[ɾʲiŋɡo]", true},
+		{"こんにちは", "[koɲɲitɕiha]", true},
+		{"がっこう", "[ɡakkoː]", true},
+		{"まっちゃ", "[mattɕa]", true},
+		{"あっ", "[aʔ]", true},
+		{"コーヒー", "[koːçiː]", true},
+		{"きょう", "[kʲoː]", true},
+		{"しゅうり", "[ɕɯːɾʲi]", true},
+		{"せんせい", "[seɴseː]", true},
+		{"おおきい", "[oːkʲiː]", true},
+		{"とうきょう", "[toːkʲoː]", true},
+		{"かわいい", "[kaɰaiː]", true},
+		{"あおい", "[aoi]", true},
+		{"ヴァイオリン", "[baioɾʲiɴ]", true},
+		{"ゔぇ", "[be]", true},
+		{"ふじさん", "[ɸɯdʑisaɴ]", true},
+		{"ちず", "[tɕidzɯ]", true},
+		{"日本", "", false},
+		{"", "", false},
+	}
+
+	for _, test := range tests {
+		got, ok := kanaToIpa(test.reading)
+		if got != test.want || ok != test.ok {
+			t.Errorf("kanaToIpa(%q) = %q, %v, want %q, %v", test.reading, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestIpaLoadWiktionary(t *testing.T) {
+	data := `{"word": "日本", "lang_code": "ja", "forms": [{"form": "にほん", "tags": ["hiragana"]}, {"form": "にっぽん", "tags": ["hiragana"]}], "sounds": [{"ipa": "[ɲihoɴ]", "other": "にほん"}, {"ipa": "[ɲippoɴ]", "other": "にっぽꜜん"}, {"ipa": "[nihon]"}]}
+{"word": "猫", "lang_code": "ja", "forms": [{"form": "ねこ", "tags": ["hiragana"]}], "sounds": [{"ipa": "[neko]"}]}
+{"word": "cat", "lang_code": "en", "sounds": [{"ipa": "/kæt/"}]}
+`
+
+	var records ipaRecords
+	if err := ipaLoadWiktionary(strings.NewReader(data), &records); err != nil {
+		t.Fatal(err)
+	}
+
+	want := dbMetaList{
+		{"日本", "ipa", phoneticData{Reading: "にほん", Transcriptions: []phoneticTranscription{{Ipa: "[ɲihoɴ]"}}}},
+		{"日本", "ipa", phoneticData{Reading: "にっぽん", Transcriptions: []phoneticTranscription{{Ipa: "[ɲippoɴ]"}}}},
+		{"猫", "ipa", phoneticData{Reading: "ねこ", Transcriptions: []phoneticTranscription{{Ipa: "[neko]"}}}},
+		{"cat", "ipa", phoneticData{Reading: "cat", Transcriptions: []phoneticTranscription{{Ipa: "/kæt/"}}}},
+	}
+	if !reflect.DeepEqual(records.metas, want) {
+		t.Errorf("ipaLoadWiktionary() = %+v, want %+v", records.metas, want)
+	}
+}
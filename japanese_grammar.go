@@ -0,0 +1,170 @@
+package yomichan
+
+import (
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+var (
+	grammarLabelNoiseExp = regexp.MustCompile(`[［\[（(〔][^］\]）)〕]*[］\]）)〕]|[\s　]`)
+
+	// Labels for parts of speech which do not inflect, or which inflect
+	// as auxiliaries, and the JMdict tags they are exported as.
+	grammarLabelPartsOfSpeech = map[string][]string{
+		"名":     {"n"},
+		"代":     {"pn"},
+		"連体":    {"adj-pn"},
+		"副":     {"adv"},
+		"副ト":    {"adv-to"},
+		"副トニ":   {"adv-to"},
+		"トニ":    {"adv-to"},
+		"副助":    {"adv", "prt"},
+		"格助":    {"prt"},
+		"係助":    {"prt"},
+		"終助":    {"prt"},
+		"間助":    {"prt"},
+		"接":     {"conj"},
+		"接続":    {"conj"},
+		"接助":    {"conj", "prt"},
+		"接尾":    {"suf"},
+		"接頭":    {"pref"},
+		"感":     {"int"},
+		"補形":    {"aux-adj"},
+		"形動":    {"adj-na"},
+		"形動ナリ":  {"adj-na"},
+		"形動タリ":  {"adj-t", "adv-to"},
+		"形動トタル": {"adj-t", "adv-to"},
+	}
+)
+
+// Strips annotations such as ［四］ from grammatical labels.
+func normalizeGrammarLabels(labels []string) []string {
+	var results []string
+	for _, label := range labels {
+		if label = grammarLabelNoiseExp.ReplaceAllLiteralString(label, ""); len(label) > 0 {
+			results = append(results, label)
+		}
+	}
+	return results
+}
+
+// Maps the grammatical labels found in 国語辞典 entries (e.g. 動カ五,
+// 自他サ変, 形, 動ハ下二) to the rules used by Yomichan for
+// deinflection: adj-i, v1, v5, vs, vk and vz. Parts of speech are
+// returned by japanesePartsOfSpeech instead.
+//
+// Classical conjugations are mapped to the modern rule which
+// deinflects their dictionary form: 四段 and ナ変 verbs end in the
+// u-row like 五段 verbs, and 二段 verbs are treated the same way.
+// ラ変 verbs and classical サ変/カ変 forms (す, く) have no
+// equivalent and receive no rule.
+//
+// When no labels are available, expressions ending in する are
+// assumed to be サ変 verbs.
+func japaneseGrammarRules(expression string, labels []string) []string {
+	var rules []string
+
+	if len(labels) == 0 {
+		if strings.HasSuffix(expression, "する") || strings.HasSuffix(expression, "為る") {
+			rules = append(rules, "vs")
+		}
+		return rules
+	}
+
+	for _, label := range normalizeGrammarLabels(labels) {
+		switch {
+		case label == "形" || label == "形ク" || label == "形シク" || label == "補形":
+			if strings.HasSuffix(expression, "い") {
+				rules = appendStringUnique(rules, "adj-i")
+			}
+		case isVerbLabel(label):
+			if rule, ok := japaneseVerbRule(expression, label); ok {
+				rules = appendStringUnique(rules, rule)
+			}
+		}
+	}
+
+	return rules
+}
+
+// Maps grammatical labels to JMdict part of speech tags, such as n,
+// adj-na or vt, which are exported as definition tags.
+func japanesePartsOfSpeech(labels []string) []string {
+	var tags []string
+
+	for _, label := range normalizeGrammarLabels(labels) {
+		if labelTags, ok := grammarLabelPartsOfSpeech[label]; ok {
+			tags = appendStringUnique(tags, labelTags...)
+			continue
+		}
+
+		if strings.HasPrefix(label, "助動") || strings.HasPrefix(label, "補動") {
+			tags = appendStringUnique(tags, "aux-v")
+		}
+
+		if isVerbLabel(label) {
+			if strings.Contains(label, "他") {
+				tags = appendStringUnique(tags, "vt")
+			}
+			if strings.Contains(label, "自") {
+				tags = appendStringUnique(tags, "vi")
+			}
+		}
+	}
+
+	return tags
+}
+
+// Returns the tags which japanesePartsOfSpeech can emit, for the tag
+// banks of dictionaries using it.
+func japaneseGrammarTags() dbTagList {
+	names := []string{"aux-v", "vt", "vi"}
+	for _, labelTags := range grammarLabelPartsOfSpeech {
+		names = appendStringUnique(names, labelTags...)
+	}
+
+	var tags dbTagList
+	for _, tag := range knownEntityTags() {
+		if slices.Contains(names, tag.Name) {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Returns true for verb labels such as 動カ五, 自五, 他下一 or 自他サ変.
+func isVerbLabel(label string) bool {
+	if !strings.ContainsAny(label, "動自他") {
+		return false
+	}
+	return strings.ContainsAny(label, "一二四五") || strings.Contains(label, "変")
+}
+
+func japaneseVerbRule(expression string, label string) (string, bool) {
+	switch {
+	case expression == "来る" || expression == "來る" || (expression == "くる" && strings.Contains(label, "カ変")):
+		return "vk", true
+	case strings.Contains(label, "サ変") || strings.Contains(label, "ザ変"):
+		if strings.HasSuffix(expression, "する") || strings.HasSuffix(expression, "為る") {
+			return "vs", true
+		} else if strings.HasSuffix(expression, "ずる") {
+			return "vz", true
+		}
+	case strings.Contains(label, "カ変") || strings.Contains(label, "ラ変"):
+		return "", false
+	case strings.Contains(label, "ナ変"):
+		return "v5", true
+	case strings.Contains(label, "一"):
+		// 蹴る is the only 下一段 verb in classical Japanese and
+		// conjugates as a 五段 verb in the modern language.
+		if strings.Contains(label, "下一") && (expression == "蹴る" || expression == "ける") {
+			return "v5", true
+		}
+		return "v1", true
+	case strings.ContainsAny(label, "二四五"):
+		return "v5", true
+	}
+	return "", false
+}
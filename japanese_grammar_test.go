@@ -0,0 +1,89 @@
+package yomichan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJapaneseGrammarRules(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		labels     []string
+		rules      []string
+		tags       []string
+	}{
+		{"五段", "書く", []string{"動カ五［四］"}, []string{"v5"}, nil},
+		{"五段 transitive", "書く", []string{"他五"}, []string{"v5"}, []string{"vt"}},
+		{"上一", "見る", []string{"動マ上一"}, []string{"v1"}, nil},
+		{"下一", "食べる", []string{"他バ下一"}, []string{"v1"}, []string{"vt"}},
+		{"下一 蹴る", "蹴る", []string{"動カ下一"}, []string{"v5"}, nil},
+		{"サ変", "勉強する", []string{"名", "自他サ変"}, []string{"vs"}, []string{"n", "vt", "vi"}},
+		{"ザ変", "信ずる", []string{"動ザ変"}, []string{"vz"}, nil},
+		{"カ変", "来る", []string{"動カ変"}, []string{"vk"}, nil},
+		{"カ変 kana", "くる", []string{"動カ変"}, []string{"vk"}, nil},
+		{"くる without カ変", "くる", []string{"動ラ五"}, []string{"v5"}, nil},
+		{"四段", "書く", []string{"動カ四"}, []string{"v5"}, nil},
+		{"上二", "起く", []string{"動カ上二"}, []string{"v5"}, nil},
+		{"下二", "受く", []string{"動カ下二"}, []string{"v5"}, nil},
+		{"ナ変", "死ぬ", []string{"動ナ変"}, []string{"v5"}, nil},
+		{"ラ変", "あり", []string{"動ラ変"}, nil, nil},
+		{"形容詞", "高い", []string{"形"}, []string{"adj-i"}, nil},
+		{"形容詞 classical", "美し", []string{"形シク"}, nil, nil},
+		{"補助形容詞", "ほしい", []string{"補形"}, []string{"adj-i"}, []string{"aux-adj"}},
+		{"形動", "静か", []string{"形動"}, nil, []string{"adj-na"}},
+		{"形動タリ", "堂堂", []string{"形動タリ"}, nil, []string{"adj-t", "adv-to"}},
+		{"助動", "たい", []string{"助動"}, nil, []string{"aux-v"}},
+		{"particle", "は", []string{"係助"}, nil, []string{"prt"}},
+	}
+
+	for _, test := range tests {
+		if rules := japaneseGrammarRules(test.expression, test.labels); !reflect.DeepEqual(rules, test.rules) {
+			t.Errorf("%s: japaneseGrammarRules(%q, %q) = %q, want %q", test.name, test.expression, test.labels, rules, test.rules)
+		}
+		if tags := japanesePartsOfSpeech(test.labels); !reflect.DeepEqual(tags, test.tags) {
+			t.Errorf("%s: japanesePartsOfSpeech(%q) = %q, want %q", test.name, test.labels, tags, test.tags)
+		}
+	}
+}
+
+// Kotowaza, wadai and shougakukan2 have no grammatical labels, so only
+// サ変 verbs are recognized from their endings.
+func TestJapaneseGrammarRulesWithoutLabels(t *testing.T) {
+	tests := []struct {
+		expression string
+		rules      []string
+	}{
+		{"勉強する", []string{"vs"}},
+		{"べんきょうする", []string{"vs"}},
+		{"為る", []string{"vs"}},
+		{"くる", nil},
+		{"たべる", nil},
+		{"猫に小判", nil},
+	}
+
+	for _, test := range tests {
+		if rules := japaneseGrammarRules(test.expression, nil); !reflect.DeepEqual(rules, test.rules) {
+			t.Errorf("japaneseGrammarRules(%q, nil) = %q, want %q", test.expression, rules, test.rules)
+		}
+	}
+
+	if tags := japanesePartsOfSpeech(nil); tags != nil {
+		t.Errorf("japanesePartsOfSpeech(nil) = %q, want nil", tags)
+	}
+}
+
+func TestJapaneseGrammarTags(t *testing.T) {
+	names := make(map[string]bool)
+	for _, tag := range japaneseGrammarTags() {
+		names[tag.Name] = true
+	}
+
+	for _, labelTags := range grammarLabelPartsOfSpeech {
+		for _, name := range labelTags {
+			if !names[name] {
+				t.Errorf("japaneseGrammarTags() is missing %q", name)
+			}
+		}
+	}
+}
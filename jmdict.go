@@ -82,6 +82,28 @@ func jmdictPublicationDate(dictionary jmdict.Jmdict) string {
 	}
 }
 
+// Loads JMdict from either the XML release or a jmdict-simplified JSON
+// file, returning the dictionary along with its entity definitions and
+// publication date.
+func loadJmdictFile(inputPath string) (jmdict.Jmdict, map[string]string, string, error) {
+	reader, err := os.Open(inputPath)
+	if err != nil {
+		return jmdict.Jmdict{}, nil, "", err
+	}
+	defer reader.Close()
+
+	if isJmdictSimplified(inputPath) {
+		return loadJmdictSimplified(reader)
+	}
+
+	dictionary, entities, err := jmdict.LoadJmdictNoTransform(reader)
+	if err != nil {
+		return jmdict.Jmdict{}, nil, "", err
+	}
+
+	return dictionary, entities, jmdictPublicationDate(dictionary), nil
+}
+
 func jmdictFormsTerm(headword headword, entry jmdict.JmdictEntry, meta jmdictMetadata) (dbTerm, bool) {
 	// Don't add "forms" terms to non-English dictionaries.
 	// Information would be duplicated if users installed more
@@ -219,18 +241,12 @@ func jmdictTerms(headword headword, entry jmdict.JmdictEntry, meta jmdictMetadat
 	return terms, true
 }
 
-func jmdictExportDb(inputPath string, outputPath string, languageName string, title string, stride int, pretty bool) error {
+func jmdictExportDb(inputPath string, outputPath string, languageName string, title string, stride int, pretty bool, options ExportOptions) error {
 	if _, ok := langNameToCode[languageName]; !ok {
 		return errors.New("Unrecognized language parameter: " + languageName)
 	}
 
-	reader, err := os.Open(inputPath)
-	if err != nil {
-		return err
-	}
-	defer reader.Close()
-
-	dictionary, entities, err := jmdict.LoadJmdictNoTransform(reader)
+	dictionary, entities, jmdictDate, err := loadJmdictFile(inputPath)
 	if err != nil {
 		return err
 	}
@@ -261,7 +277,6 @@ func jmdictExportDb(inputPath string, outputPath string, languageName string, ti
 	if title == "" {
 		title = "JMdict"
 	}
-	jmdictDate := jmdictPublicationDate(dictionary)
 
 	index := dbIndex{
 		Title:       title,
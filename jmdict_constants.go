@@ -37,6 +37,7 @@ var ISOtoFlag = map[string]string{
 	"hun": "'🇭🇺 '",
 	"ita": "'🇮🇹 '",
 	"jpn": "'🇯🇵 '",
+	"por": "'🇵🇹 '",
 	"rus": "'🇷🇺 '",
 	"slv": "'🇸🇮 '",
 	"spa": "'🇪🇸 '",
@@ -1,7 +1,6 @@
 package yomichan
 
 import (
-	"os"
 	"strings"
 
 	"foosoft.net/projects/jmdict"
@@ -189,14 +188,8 @@ func baseFormsTerm(entry jmdict.JmdictEntry, meta jmdictMetadata) dbTerm {
 	return term
 }
 
-func formsExportDb(inputPath, outputPath, languageName, title string, stride int, pretty bool) error {
-	reader, err := os.Open(inputPath)
-	if err != nil {
-		return err
-	}
-	defer reader.Close()
-
-	dictionary, entities, err := jmdict.LoadJmdictNoTransform(reader)
+func formsExportDb(inputPath, outputPath, languageName, title string, stride int, pretty bool, options ExportOptions) error {
+	dictionary, entities, jmdictDate, err := loadJmdictFile(inputPath)
 	if err != nil {
 		return err
 	}
@@ -237,8 +230,6 @@ func formsExportDb(inputPath, outputPath, languageName, title string, stride int
 		"tag":  tags.crush(),
 	}
 
-	jmdictDate := jmdictPublicationDate(dictionary)
-
 	index := dbIndex{
 		Title:       title,
 		Revision:    "JMdict." + jmdictDate,
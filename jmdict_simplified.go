@@ -0,0 +1,274 @@
+package yomichan
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"foosoft.net/projects/jmdict"
+)
+
+// The JSON format of the jmdict-simplified project, see
+// https://github.com/scriptin/jmdict-simplified
+type jmdictSimplified struct {
+	DictDate string                 `json:"dictDate"`
+	Tags     map[string]string      `json:"tags"`
+	Words    []jmdictSimplifiedWord `json:"words"`
+}
+
+type jmdictSimplifiedWord struct {
+	ID    string                  `json:"id"`
+	Kanji []jmdictSimplifiedKanji `json:"kanji"`
+	Kana  []jmdictSimplifiedKana  `json:"kana"`
+	Sense []jmdictSimplifiedSense `json:"sense"`
+}
+
+type jmdictSimplifiedKanji struct {
+	Common bool     `json:"common"`
+	Text   string   `json:"text"`
+	Tags   []string `json:"tags"`
+}
+
+type jmdictSimplifiedKana struct {
+	Common         bool     `json:"common"`
+	Text           string   `json:"text"`
+	Tags           []string `json:"tags"`
+	AppliesToKanji []string `json:"appliesToKanji"`
+}
+
+type jmdictSimplifiedSense struct {
+	PartOfSpeech   []string                  `json:"partOfSpeech"`
+	AppliesToKanji []string                  `json:"appliesToKanji"`
+	AppliesToKana  []string                  `json:"appliesToKana"`
+	Related        [][]any                   `json:"related"`
+	Antonym        [][]any                   `json:"antonym"`
+	Field          []string                  `json:"field"`
+	Dialect        []string                  `json:"dialect"`
+	Misc           []string                  `json:"misc"`
+	Info           []string                  `json:"info"`
+	LanguageSource []jmdictSimplifiedSource  `json:"languageSource"`
+	Gloss          []jmdictSimplifiedGloss   `json:"gloss"`
+	Examples       []jmdictSimplifiedExample `json:"examples"`
+}
+
+type jmdictSimplifiedSource struct {
+	Lang  string  `json:"lang"`
+	Full  bool    `json:"full"`
+	Wasei bool    `json:"wasei"`
+	Text  *string `json:"text"`
+}
+
+type jmdictSimplifiedGloss struct {
+	Lang   string  `json:"lang"`
+	Gender *string `json:"gender"`
+	Type   *string `json:"type"`
+	Text   string  `json:"text"`
+}
+
+type jmdictSimplifiedExample struct {
+	Source struct {
+		Type  string `json:"type"`
+		Value string `json:"value"`
+	} `json:"source"`
+	Text      string `json:"text"`
+	Sentences []struct {
+		// the jmdict-simplified schema spells the language "land";
+		// "lang" is read as well in case a later release corrects it
+		Land string `json:"land"`
+		Lang string `json:"lang"`
+		Text string `json:"text"`
+	} `json:"sentences"`
+}
+
+// Gloss types are spelled out in jmdict-simplified, but abbreviated
+// in the JMdict XML.
+var jmdictSimplifiedGlossTypes = map[string]string{
+	"literal":     "lit",
+	"figurative":  "fig",
+	"explanation": "expl",
+	"trademark":   "tm",
+}
+
+// Returns true if the file looks like a jmdict-simplified JSON file,
+// which begins with a header containing the dictionary date and tags.
+func isJmdictSimplified(path string) bool {
+	fp, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer fp.Close()
+
+	header := make([]byte, 4096)
+	n, _ := io.ReadFull(fp, header)
+	header = header[:n]
+
+	return bytes.HasPrefix(bytes.TrimSpace(header), []byte("{")) &&
+		bytes.Contains(header, []byte(`"dictDate"`)) &&
+		bytes.Contains(header, []byte(`"tags"`))
+}
+
+// Converts everything except "*" (which applies to all headwords) into
+// a restriction list.
+func jmdictSimplifiedRestrictions(appliesTo []string) []string {
+	if len(appliesTo) == 1 && appliesTo[0] == "*" {
+		return nil
+	}
+	return appliesTo
+}
+
+// Converts a cross reference such as ["丸", "まる", 1] into the JMdict
+// XML notation "丸・まる・1".
+func jmdictSimplifiedReferences(references [][]any) []string {
+	var results []string
+	for _, reference := range references {
+		var parts []string
+		for _, part := range reference {
+			switch v := part.(type) {
+			case string:
+				parts = append(parts, v)
+			case float64:
+				parts = append(parts, strconv.Itoa(int(v)))
+			}
+		}
+		results = append(results, strings.Join(parts, "・"))
+	}
+	return results
+}
+
+func convertJmdictSimplifiedSense(sense jmdictSimplifiedSense) jmdict.JmdictSense {
+	result := jmdict.JmdictSense{
+		RestrictedKanji:    jmdictSimplifiedRestrictions(sense.AppliesToKanji),
+		RestrictedReadings: jmdictSimplifiedRestrictions(sense.AppliesToKana),
+		References:         jmdictSimplifiedReferences(sense.Related),
+		Antonyms:           jmdictSimplifiedReferences(sense.Antonym),
+		PartsOfSpeech:      sense.PartOfSpeech,
+		Fields:             sense.Field,
+		Misc:               sense.Misc,
+		Dialects:           sense.Dialect,
+		Information:        sense.Info,
+	}
+
+	for _, source := range sense.LanguageSource {
+		converted := jmdict.JmdictSource{}
+		if source.Text != nil {
+			converted.Content = *source.Text
+		}
+		if source.Lang != "eng" {
+			lang := source.Lang
+			converted.Language = &lang
+		}
+		if !source.Full {
+			part := "part"
+			converted.Type = &part
+		}
+		if source.Wasei {
+			converted.Wasei = "y"
+		}
+		result.SourceLanguages = append(result.SourceLanguages, converted)
+	}
+
+	for _, gloss := range sense.Gloss {
+		converted := jmdict.JmdictGlossary{
+			Content: gloss.Text,
+			Gender:  gloss.Gender,
+		}
+		if gloss.Lang != "eng" {
+			lang := gloss.Lang
+			converted.Language = &lang
+		}
+		if gloss.Type != nil {
+			glossType := *gloss.Type
+			if abbreviation, ok := jmdictSimplifiedGlossTypes[glossType]; ok {
+				glossType = abbreviation
+			}
+			converted.Type = &glossType
+		}
+		result.Glossary = append(result.Glossary, converted)
+	}
+
+	for _, example := range sense.Examples {
+		converted := jmdict.JmdictExample{
+			SourceItem: jmdict.JmdictExampleSource{
+				Value: example.Source.Value,
+				Type:  example.Source.Type,
+			},
+			Text: example.Text,
+		}
+		for _, sentence := range example.Sentences {
+			lang := sentence.Land
+			if lang == "" {
+				lang = sentence.Lang
+			}
+			converted.Sentences = append(converted.Sentences, jmdict.JmdictExampleSentence{
+				Text: sentence.Text,
+				Lang: lang,
+			})
+		}
+		result.Examples = append(result.Examples, converted)
+	}
+
+	return result
+}
+
+// Converts a jmdict-simplified word into the structure produced by
+// the JMdict XML parser. Headwords marked as common are given the
+// "news1" priority, which marks them as priority terms without adding
+// any frequency tags; the individual priority lists are not included
+// in jmdict-simplified.
+func convertJmdictSimplifiedEntry(word jmdictSimplifiedWord) jmdict.JmdictEntry {
+	sequence, _ := strconv.Atoi(word.ID)
+	entry := jmdict.JmdictEntry{Sequence: sequence}
+
+	priorities := func(common bool) []string {
+		if common {
+			return []string{"news1"}
+		}
+		return nil
+	}
+
+	for _, kanji := range word.Kanji {
+		entry.Kanji = append(entry.Kanji, jmdict.JmdictKanji{
+			Expression:  kanji.Text,
+			Information: kanji.Tags,
+			Priorities:  priorities(kanji.Common),
+		})
+	}
+
+	for _, kana := range word.Kana {
+		reading := jmdict.JmdictReading{
+			Reading:      kana.Text,
+			Restrictions: jmdictSimplifiedRestrictions(kana.AppliesToKanji),
+			Information:  kana.Tags,
+			Priorities:   priorities(kana.Common),
+		}
+		// equivalent to <re_nokanji/> in the XML
+		if len(kana.AppliesToKanji) == 0 && len(word.Kanji) > 0 {
+			noKanji := ""
+			reading.NoKanji = &noKanji
+		}
+		entry.Readings = append(entry.Readings, reading)
+	}
+
+	for _, sense := range word.Sense {
+		entry.Sense = append(entry.Sense, convertJmdictSimplifiedSense(sense))
+	}
+
+	return entry
+}
+
+func loadJmdictSimplified(reader io.Reader) (jmdict.Jmdict, map[string]string, string, error) {
+	var data jmdictSimplified
+	if err := json.NewDecoder(reader).Decode(&data); err != nil {
+		return jmdict.Jmdict{}, nil, "", err
+	}
+
+	var dictionary jmdict.Jmdict
+	for _, word := range data.Words {
+		dictionary.Entries = append(dictionary.Entries, convertJmdictSimplifiedEntry(word))
+	}
+
+	return dictionary, data.Tags, data.DictDate, nil
+}
@@ -0,0 +1,36 @@
+package yomichan
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestConvertJmdictSimplifiedExamples(t *testing.T) {
+	data := `{
+		"partOfSpeech": ["n"],
+		"gloss": [{"lang": "eng", "text": "cat"}],
+		"examples": [{
+			"source": {"type": "tatoeba", "value": "1"},
+			"text": "猫",
+			"sentences": [
+				{"land": "jpn", "text": "猫が好き。"},
+				{"lang": "eng", "text": "I like cats."}
+			]
+		}]
+	}`
+
+	var sense jmdictSimplifiedSense
+	if err := json.Unmarshal([]byte(data), &sense); err != nil {
+		t.Fatal(err)
+	}
+
+	examples := convertJmdictSimplifiedSense(sense).Examples
+	if len(examples) != 1 || len(examples[0].Sentences) != 2 {
+		t.Fatalf("convertJmdictSimplifiedSense() examples = %+v", examples)
+	}
+	for i, want := range []string{"jpn", "eng"} {
+		if lang := examples[0].Sentences[i].Lang; lang != want {
+			t.Errorf("sentence %d language = %q, want %q", i, lang, want)
+		}
+	}
+}
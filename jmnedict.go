@@ -81,7 +81,7 @@ func jmnedictHeadwords(entry jmdict.JmnedictEntry) (headwords []headword) {
 	return headwords
 }
 
-func jmnedictExportDb(inputPath, outputPath, language, title string, stride int, pretty bool) error {
+func jmnedictExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
 	reader, err := os.Open(inputPath)
 	if err != nil {
 		return err
@@ -18,6 +18,19 @@ func katakanaToHiragana(text string) string {
 	return strings.Map(f, text)
 }
 
+// Returns true if the text only consists of hiragana and katakana.
+func isKanaOnly(text string) bool {
+	if text == "" {
+		return false
+	}
+	for _, char := range text {
+		if (char < 'ぁ' || char > 'ヿ') && char != '〜' {
+			return false
+		}
+	}
+	return true
+}
+
 // Replace hiragana iteration marks with the appropriate characters.
 // E.g. "さゝき" -> "ささき"; "たゞの" -> "ただの"
 func replaceIterationMarks(text string) string {
@@ -1,13 +1,91 @@
 package yomichan
 
 import (
+	"fmt"
 	"os"
+	"sort"
 	"strconv"
+	"strings"
 
 	"foosoft.net/projects/jmdict"
+	"golang.org/x/exp/slices"
 )
 
-func kanjidicExtractKanji(entry jmdict.KanjidicCharacter, language string) *dbKanji {
+// Reading types which can be added to the kanji stats in addition to
+// the on and kun readings and the name readings (nanori).
+var kanjidicExtraReadings = []string{"pinyin", "korean_r", "korean_h", "vietnam"}
+
+// Resolves the reading types selected on the command line, where "all"
+// selects every extra reading type.
+func kanjidicReadingTypes(names []string) ([]string, error) {
+	var types []string
+	for _, name := range names {
+		if name == "all" {
+			return kanjidicExtraReadings, nil
+		}
+		if !slices.Contains(kanjidicExtraReadings, name) {
+			return nil, fmt.Errorf("unsupported kanji reading type: %s", name)
+		}
+		types = appendStringUnique(types, name)
+	}
+	return types, nil
+}
+
+// Adds the name readings (nanori), which KANJIDIC2 lists separately
+// from the other readings, and the selected extra readings to the
+// kanji stats as lists. Pinyin is written with tone marks.
+func kanjidicExtractReadings(kanji *dbKanji, entry jmdict.KanjidicCharacter, readingTypes []string) {
+	if nanori := entry.ReadingMeaning.Nanori; len(nanori) > 0 {
+		kanji.Stats["nanori"] = strings.Join(nanori, ", ")
+	}
+
+	readings := make(map[string][]string)
+	for _, r := range entry.ReadingMeaning.Readings {
+		if r.Type == "pinyin" {
+			readings[r.Type] = append(readings[r.Type], cedictSyllableToMarks(r.Value))
+		} else {
+			readings[r.Type] = append(readings[r.Type], r.Value)
+		}
+	}
+
+	for _, readingType := range readingTypes {
+		if values := readings[readingType]; len(values) > 0 {
+			kanji.Stats[readingType] = strings.Join(values, ", ")
+		}
+	}
+}
+
+// Meaning languages found in KANJIDIC2. JMdict has no Portuguese
+// glosses, so its name is resolved here rather than in langNameToCode.
+var kanjidicMeaningLanguages = []string{"eng", "fre", "por", "spa"}
+
+// Returns the m_lang value KANJIDIC2 uses for meanings in a language:
+// none for English and the ISO 639-1 code otherwise.
+func kanjidicMeaningTag(code string) string {
+	if code == "eng" {
+		return ""
+	}
+	return ISOtoHTML[code]
+}
+
+// Resolves a comma-separated list of language names into language codes.
+func kanjidicLanguages(language string) ([]string, error) {
+	var codes []string
+	for _, name := range strings.Split(language, ",") {
+		name = strings.TrimSpace(name)
+		code, ok := langNameToCode[name]
+		if name == "portuguese" {
+			code, ok = "por", true
+		}
+		if !ok || !slices.Contains(kanjidicMeaningLanguages, code) {
+			return nil, fmt.Errorf("unsupported kanji meaning language: %s", name)
+		}
+		codes = appendStringUnique(codes, code)
+	}
+	return codes, nil
+}
+
+func kanjidicExtractKanji(entry jmdict.KanjidicCharacter, languages []string, readingTypes []string, components map[string][]string) *dbKanji {
 	if entry.ReadingMeaning == nil {
 		return nil
 	}
@@ -17,9 +95,18 @@ func kanjidicExtractKanji(entry jmdict.KanjidicCharacter, language string) *dbKa
 		Stats:     make(map[string]string),
 	}
 
-	for _, m := range entry.ReadingMeaning.Meanings {
-		if m.Language == nil && language == "" || m.Language != nil && language == *m.Language {
-			kanji.Meanings = append(kanji.Meanings, m.Meaning)
+	// meanings are prefixed with flags when several languages are shown
+	for _, code := range languages {
+		var flag string
+		if len(languages) > 1 {
+			flag = strings.Trim(ISOtoFlag[code], "'")
+		}
+
+		tag := kanjidicMeaningTag(code)
+		for _, m := range entry.ReadingMeaning.Meanings {
+			if m.Language == nil && tag == "" || m.Language != nil && tag == *m.Language {
+				kanji.Meanings = append(kanji.Meanings, flag+m.Meaning)
+			}
 		}
 	}
 
@@ -37,6 +124,7 @@ func kanjidicExtractKanji(entry jmdict.KanjidicCharacter, language string) *dbKa
 
 	if level := entry.Misc.JlptLevel; level != nil {
 		kanji.Stats["jlpt"] = *level
+		kanji.addTags("jlpt" + *level)
 	}
 
 	if counts := entry.Misc.StrokeCounts; len(counts) > 0 {
@@ -56,6 +144,11 @@ func kanjidicExtractKanji(entry jmdict.KanjidicCharacter, language string) *dbKa
 		if gradeInt, err := strconv.Atoi(*grade); err == nil {
 			if gradeInt >= 1 && gradeInt <= 8 {
 				kanji.addTags("jouyou")
+				if gradeInt <= 6 {
+					kanji.addTags("grade" + *grade)
+				} else {
+					kanji.addTags("secondary")
+				}
 			} else if gradeInt >= 9 && gradeInt <= 10 {
 				kanji.addTags("jinmeiyou")
 			}
@@ -71,10 +164,171 @@ func kanjidicExtractKanji(entry jmdict.KanjidicCharacter, language string) *dbKa
 		}
 	}
 
+	kanjidicExtractReadings(&kanji, entry, readingTypes)
+
+	for _, radical := range entry.Radical {
+		if radical.Type == "classical" {
+			if description, ok := kangxiRadicalDescription(radical.Value); ok {
+				kanji.Stats["radical"] = description
+			}
+		}
+	}
+
+	if parts := components[entry.Literal]; len(parts) > 0 {
+		kanji.Stats["components"] = strings.Join(parts, " ")
+	}
+
 	return &kanji
 }
 
-func kanjidicExportDb(inputPath, outputPath, language, title string, stride int, pretty bool) error {
+// Maps the types of KANJIDIC2 variant references to the type of code
+// or dictionary number they refer to.
+var kanjidicVariantTypes = map[string]string{
+	"jis208":   "jis208",
+	"jis212":   "jis212",
+	"jis213":   "jis213",
+	"ucs":      "ucs",
+	"deroo":    "deroo",
+	"s_h":      "sh_desc",
+	"njecd":    "halpern_njecd",
+	"nelson_c": "nelson_c",
+	"oneill":   "oneill_names",
+}
+
+// Normalizes codes so that references match the values of the
+// characters they refer to, e.g. "1-48-19" and "48-19" for JIS X 0208.
+func kanjidicVariantKey(codeType, value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if strings.HasPrefix(codeType, "jis") {
+		parts := strings.Split(value, "-")
+		if codeType == "jis208" && len(parts) == 3 {
+			parts = parts[1:]
+		}
+		for i, part := range parts {
+			if number, err := strconv.Atoi(part); err == nil {
+				parts[i] = strconv.Itoa(number)
+			}
+		}
+		value = strings.Join(parts, "-")
+	}
+	return codeType + ":" + value
+}
+
+// Builds a table from codes and dictionary numbers to the characters
+// which have them, used to resolve variant references.
+func kanjidicVariantTable(characters []jmdict.KanjidicCharacter) map[string]string {
+	table := make(map[string]string)
+	add := func(codeType, value, literal string) {
+		key := kanjidicVariantKey(codeType, value)
+		if _, ok := table[key]; !ok {
+			table[key] = literal
+		}
+	}
+
+	for _, entry := range characters {
+		for _, code := range entry.Codepoint {
+			add(code.Type, code.Value, entry.Literal)
+		}
+		for _, number := range entry.DictionaryNumbers {
+			add(number.Type, number.Value, entry.Literal)
+		}
+		for _, code := range entry.QueryCode {
+			add(code.Type, code.Value, entry.Literal)
+		}
+	}
+
+	return table
+}
+
+// Resolves the variant references of a character into characters.
+// Unicode references which are not in the table are decoded directly.
+func kanjidicResolveVariants(entry jmdict.KanjidicCharacter, table map[string]string) []string {
+	var variants []string
+	for _, variant := range entry.Misc.Variants {
+		codeType, ok := kanjidicVariantTypes[variant.Type]
+		if !ok {
+			continue
+		}
+
+		literal, ok := table[kanjidicVariantKey(codeType, variant.Value)]
+		if !ok && codeType == "ucs" {
+			if codepoint, err := strconv.ParseInt(variant.Value, 16, 32); err == nil {
+				literal, ok = string(rune(codepoint)), true
+			}
+		}
+
+		if ok && literal != entry.Literal {
+			variants = appendStringUnique(variants, literal)
+		}
+	}
+	return variants
+}
+
+// Builds entries for variant characters which have no meanings of their
+// own, pointing to the standard forms which list them as variants.
+func kanjidicVariantKanji(kanji dbKanjiList, characters map[string]jmdict.KanjidicCharacter, standards map[string][]string) dbKanjiList {
+	exported := make(map[string]bool)
+	for _, k := range kanji {
+		exported[k.Character] = true
+	}
+
+	var variants []string
+	for variant := range standards {
+		if !exported[variant] {
+			variants = append(variants, variant)
+		}
+	}
+	sort.Strings(variants)
+
+	var results dbKanjiList
+	for _, variant := range variants {
+		result := dbKanji{
+			Character: variant,
+			Stats:     map[string]string{"variants": strings.Join(standards[variant], ", ")},
+		}
+		result.addTags("variant")
+
+		for _, standard := range standards[variant] {
+			result.Meanings = append(result.Meanings, "variant of "+standard)
+		}
+
+		if entry, ok := characters[variant]; ok {
+			if entry.ReadingMeaning != nil {
+				for _, r := range entry.ReadingMeaning.Readings {
+					switch r.Type {
+					case "ja_on":
+						result.Onyomi = append(result.Onyomi, r.Value)
+					case "ja_kun":
+						result.Kunyomi = append(result.Kunyomi, r.Value)
+					}
+				}
+			}
+			if counts := entry.Misc.StrokeCounts; len(counts) > 0 {
+				result.Stats["strokes"] = counts[0]
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// Reports characters whose stroke count in KanjiVG differs from the
+// accepted stroke count in KANJIDIC2.
+func kanjidicCheckStrokeCounts(characters []jmdict.KanjidicCharacter, strokes map[string][]string) {
+	for _, entry := range characters {
+		paths, ok := strokes[entry.Literal]
+		if !ok || len(entry.Misc.StrokeCounts) == 0 {
+			continue
+		}
+		if count, err := strconv.Atoi(entry.Misc.StrokeCounts[0]); err == nil && count != len(paths) {
+			fmt.Printf("Stroke count mismatch for %s: KANJIDIC2 has %d, KanjiVG has %d\n", entry.Literal, count, len(paths))
+		}
+	}
+}
+
+func kanjidicExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
 	reader, err := os.Open(inputPath)
 	if err != nil {
 		return err
@@ -86,24 +340,81 @@ func kanjidicExportDb(inputPath, outputPath, language, title string, stride int,
 		return err
 	}
 
-	var langTag string
-	switch language {
-	case "french":
-		langTag = "fr"
-	case "spanish":
-		langTag = "es"
-	case "portuguese":
-		langTag = "pt"
+	languages, err := kanjidicLanguages(language)
+	if err != nil {
+		return err
 	}
 
-	var kanji dbKanjiList
+	readingTypes, err := kanjidicReadingTypes(options.KanjiReadings)
+	if err != nil {
+		return err
+	}
+
+	components, err := loadKanjiComponents(options.Kradfiles, options.Radkfile)
+	if err != nil {
+		return err
+	}
+
+	var (
+		kanji        dbKanjiList
+		frequencies  dbMetaList
+		variantTable = kanjidicVariantTable(dict.Characters)
+		characters   = make(map[string]jmdict.KanjidicCharacter)
+		standards    = make(map[string][]string)
+	)
+
 	for _, entry := range dict.Characters {
-		kanjiCurr := kanjidicExtractKanji(entry, langTag)
+		characters[entry.Literal] = entry
+	}
+
+	for _, entry := range dict.Characters {
+		kanjiCurr := kanjidicExtractKanji(entry, languages, readingTypes, components)
 		if kanjiCurr != nil {
+			// newspaper frequency ranks, with 1 being the most frequent
+			if frequency := entry.Misc.Frequency; frequency != nil {
+				if rank, err := strconv.Atoi(*frequency); err == nil {
+					frequencies = append(frequencies, dbMeta{entry.Literal, "freq", rank})
+				}
+			}
+			variants := kanjidicResolveVariants(entry, variantTable)
+			if len(variants) > 0 {
+				kanjiCurr.Stats["variants"] = strings.Join(variants, ", ")
+			}
+			for _, variant := range variants {
+				standards[variant] = appendStringUnique(standards[variant], entry.Literal)
+			}
 			kanji = append(kanji, *kanjiCurr)
 		}
 	}
 
+	if options.KanjiVariants {
+		kanji = append(kanji, kanjidicVariantKanji(kanji, characters, standards)...)
+	}
+
+	var (
+		terms dbTermList
+		media map[string][]byte
+	)
+
+	if options.KanjiVG != "" {
+		strokes, err := loadKanjiVG(options.KanjiVG)
+		if err != nil {
+			return err
+		}
+
+		kanjidicCheckStrokeCounts(dict.Characters, strokes)
+
+		// term entries show up on every lookup of a single kanji, so
+		// they are only added on request
+		if options.KanjiVGTerms {
+			var literals []string
+			for _, k := range kanji {
+				literals = append(literals, k.Character)
+			}
+			terms, media = kanjivgTerms(strokes, literals, options.KanjiVGColors)
+		}
+	}
+
 	if title == "" {
 		title = "KANJIDIC2"
 	}
@@ -111,12 +422,35 @@ func kanjidicExportDb(inputPath, outputPath, language, title string, stride int,
 	tags := dbTagList{
 		dbTag{Name: "jouyou", Notes: "included in list of regular-use characters", Category: "frequent", Order: -5},
 		dbTag{Name: "jinmeiyou", Notes: "included in list of characters for use in personal names", Category: "frequent", Order: -5},
+		dbTag{Name: "grade1", Notes: "taught in grade 1 of elementary school", Category: "frequent", Order: -5},
+		dbTag{Name: "grade2", Notes: "taught in grade 2 of elementary school", Category: "frequent", Order: -5},
+		dbTag{Name: "grade3", Notes: "taught in grade 3 of elementary school", Category: "frequent", Order: -5},
+		dbTag{Name: "grade4", Notes: "taught in grade 4 of elementary school", Category: "frequent", Order: -5},
+		dbTag{Name: "grade5", Notes: "taught in grade 5 of elementary school", Category: "frequent", Order: -5},
+		dbTag{Name: "grade6", Notes: "taught in grade 6 of elementary school", Category: "frequent", Order: -5},
+		dbTag{Name: "secondary", Notes: "regular-use character taught in secondary school", Category: "frequent", Order: -5},
+		dbTag{Name: "jlpt1", Notes: "included in JLPT level 1 (pre-2010 levels)", Category: "popular", Order: -6},
+		dbTag{Name: "jlpt2", Notes: "included in JLPT level 2 (pre-2010 levels)", Category: "popular", Order: -6},
+		dbTag{Name: "jlpt3", Notes: "included in JLPT level 3 (pre-2010 levels)", Category: "popular", Order: -6},
+		dbTag{Name: "jlpt4", Notes: "included in JLPT level 4 (pre-2010 levels)", Category: "popular", Order: -6},
+		dbTag{Name: "variant", Notes: "variant form of another character", Category: "archaism", Order: -4},
 
 		dbTag{Name: "freq", Notes: "Frequency", Category: "misc"},
 		dbTag{Name: "grade", Notes: "Grade level", Category: "misc"},
 		dbTag{Name: "jlpt", Notes: "JLPT level", Category: "misc"},
 		dbTag{Name: "strokes", Notes: "Stroke count", Category: "misc"},
 
+		dbTag{Name: "radical", Notes: "Classical radical", Category: "misc"},
+		dbTag{Name: "components", Notes: "Components", Category: "misc"},
+		dbTag{Name: "variants", Notes: "Variant forms", Category: "misc"},
+
+		dbTag{Name: "nanori", Notes: "Name readings (nanori)", Category: "reading"},
+
+		dbTag{Name: "pinyin", Notes: "Chinese reading (pinyin)", Category: "misc"},
+		dbTag{Name: "korean_r", Notes: "Korean reading (romanized)", Category: "misc"},
+		dbTag{Name: "korean_h", Notes: "Korean reading (hangul)", Category: "misc"},
+		dbTag{Name: "vietnam", Notes: "Vietnamese reading", Category: "misc"},
+
 		dbTag{Name: "jis208", Notes: "JIS X 0208-1997 kuten code", Category: "code"},
 		dbTag{Name: "jis212", Notes: "JIS X 0212-1990 kuten code", Category: "code"},
 		dbTag{Name: "jis213", Notes: "JIS X 0213-2000 kuten code", Category: "code"},
@@ -155,21 +489,29 @@ func kanjidicExportDb(inputPath, outputPath, language, title string, stride int,
 	}
 
 	recordData := map[string]dbRecordList{
-		"kanji": kanji.crush(),
-		"tag":   tags.crush(),
+		"kanji":      kanji.crush(),
+		"kanji_meta": frequencies.crush(),
+		"term":       terms.crush(),
+		"tag":        tags.crush(),
 	}
 
 	index := dbIndex{
-		Title:       title,
-		Revision:    "kanjidic2",
-		Sequenced:   false,
-		Attribution: edrdgAttribution,
+		Title:         title,
+		Revision:      "kanjidic2",
+		Sequenced:     false,
+		Attribution:   edrdgAttribution,
+		FrequencyMode: "rank-based",
+	}
+
+	if len(terms) > 0 {
+		index.Attribution += " " + kanjivgAttribution
 	}
 
-	return writeDb(
+	return writeDbWithMedia(
 		outputPath,
 		index,
 		recordData,
+		media,
 		stride,
 		pretty,
 	)
@@ -0,0 +1,408 @@
+package yomichan
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/japanese"
+)
+
+type kangxiRadical struct {
+	character string
+	name      string
+	meaning   string
+}
+
+// The 214 classical (Kangxi) radicals, indexed by radical number minus
+// one, with their Japanese names and English meanings.
+var kangxiRadicals = [214]kangxiRadical{
+	{"一", "いち", "one"},
+	{"丨", "ぼう", "line"},
+	{"丶", "てん", "dot"},
+	{"丿", "の", "slash"},
+	{"乙", "おつ", "second"},
+	{"亅", "はねぼう", "hook"},
+	{"二", "に", "two"},
+	{"亠", "なべぶた", "lid"},
+	{"人", "ひと", "person"},
+	{"儿", "ひとあし", "legs"},
+	{"入", "いる", "enter"},
+	{"八", "はち", "eight"},
+	{"冂", "けいがまえ", "down box"},
+	{"冖", "わかんむり", "cover"},
+	{"冫", "にすい", "ice"},
+	{"几", "つくえ", "table"},
+	{"凵", "うけばこ", "open box"},
+	{"刀", "かたな", "knife"},
+	{"力", "ちから", "power"},
+	{"勹", "つつみがまえ", "wrap"},
+	{"匕", "さじ", "spoon"},
+	{"匚", "はこがまえ", "box"},
+	{"匸", "かくしがまえ", "hiding enclosure"},
+	{"十", "じゅう", "ten"},
+	{"卜", "ぼく", "divination"},
+	{"卩", "ふしづくり", "seal"},
+	{"厂", "がんだれ", "cliff"},
+	{"厶", "む", "private"},
+	{"又", "また", "again"},
+	{"口", "くち", "mouth"},
+	{"囗", "くにがまえ", "enclosure"},
+	{"土", "つち", "earth"},
+	{"士", "さむらい", "scholar"},
+	{"夂", "ふゆがしら", "go"},
+	{"夊", "すいにょう", "go slowly"},
+	{"夕", "ゆうべ", "evening"},
+	{"大", "だい", "big"},
+	{"女", "おんな", "woman"},
+	{"子", "こ", "child"},
+	{"宀", "うかんむり", "roof"},
+	{"寸", "すん", "inch"},
+	{"小", "しょう", "small"},
+	{"尢", "だいのまげあし", "lame"},
+	{"尸", "しかばね", "corpse"},
+	{"屮", "てつ", "sprout"},
+	{"山", "やま", "mountain"},
+	{"巛", "かわ", "river"},
+	{"工", "たくみ", "work"},
+	{"己", "おのれ", "oneself"},
+	{"巾", "はば", "turban"},
+	{"干", "かん", "dry"},
+	{"幺", "いとがしら", "short thread"},
+	{"广", "まだれ", "dotted cliff"},
+	{"廴", "えんにょう", "long stride"},
+	{"廾", "にじゅうあし", "two hands"},
+	{"弋", "しきがまえ", "shoot"},
+	{"弓", "ゆみ", "bow"},
+	{"彐", "けいがしら", "snout"},
+	{"彡", "さんづくり", "bristle"},
+	{"彳", "ぎょうにんべん", "step"},
+	{"心", "こころ", "heart"},
+	{"戈", "ほこづくり", "halberd"},
+	{"戶", "と", "door"},
+	{"手", "て", "hand"},
+	{"支", "しにょう", "branch"},
+	{"攴", "ぼくづくり", "rap"},
+	{"文", "ぶん", "script"},
+	{"斗", "とます", "dipper"},
+	{"斤", "おのづくり", "axe"},
+	{"方", "ほう", "square"},
+	{"无", "むにょう", "not"},
+	{"日", "ひ", "sun"},
+	{"曰", "いわく", "say"},
+	{"月", "つき", "moon"},
+	{"木", "き", "tree"},
+	{"欠", "あくび", "lack"},
+	{"止", "とめる", "stop"},
+	{"歹", "がつへん", "death"},
+	{"殳", "るまた", "weapon"},
+	{"毋", "なかれ", "do not"},
+	{"比", "くらべる", "compare"},
+	{"毛", "け", "fur"},
+	{"氏", "うじ", "clan"},
+	{"气", "きがまえ", "steam"},
+	{"水", "みず", "water"},
+	{"火", "ひ", "fire"},
+	{"爪", "つめ", "claw"},
+	{"父", "ちち", "father"},
+	{"爻", "こう", "double x"},
+	{"爿", "しょうへん", "split wood"},
+	{"片", "かた", "slice"},
+	{"牙", "きば", "fang"},
+	{"牛", "うし", "cow"},
+	{"犬", "いぬ", "dog"},
+	{"玄", "げん", "profound"},
+	{"玉", "たま", "jade"},
+	{"瓜", "うり", "melon"},
+	{"瓦", "かわら", "tile"},
+	{"甘", "あまい", "sweet"},
+	{"生", "うまれる", "life"},
+	{"用", "もちいる", "use"},
+	{"田", "た", "field"},
+	{"疋", "ひき", "bolt of cloth"},
+	{"疒", "やまいだれ", "sickness"},
+	{"癶", "はつがしら", "footsteps"},
+	{"白", "しろ", "white"},
+	{"皮", "けがわ", "skin"},
+	{"皿", "さら", "dish"},
+	{"目", "め", "eye"},
+	{"矛", "ほこ", "spear"},
+	{"矢", "や", "arrow"},
+	{"石", "いし", "stone"},
+	{"示", "しめす", "spirit"},
+	{"禸", "ぐうのあし", "track"},
+	{"禾", "のぎ", "grain"},
+	{"穴", "あな", "cave"},
+	{"立", "たつ", "stand"},
+	{"竹", "たけ", "bamboo"},
+	{"米", "こめ", "rice"},
+	{"糸", "いと", "silk"},
+	{"缶", "ほとぎ", "jar"},
+	{"网", "あみがしら", "net"},
+	{"羊", "ひつじ", "sheep"},
+	{"羽", "はね", "feather"},
+	{"老", "おいかんむり", "old"},
+	{"而", "しこうして", "and"},
+	{"耒", "すきへん", "plow"},
+	{"耳", "みみ", "ear"},
+	{"聿", "ふでづくり", "brush"},
+	{"肉", "にく", "meat"},
+	{"臣", "しん", "minister"},
+	{"自", "みずから", "self"},
+	{"至", "いたる", "arrive"},
+	{"臼", "うす", "mortar"},
+	{"舌", "した", "tongue"},
+	{"舛", "まいあし", "oppose"},
+	{"舟", "ふね", "boat"},
+	{"艮", "こんづくり", "stopping"},
+	{"色", "いろ", "color"},
+	{"艸", "くさかんむり", "grass"},
+	{"虍", "とらかんむり", "tiger"},
+	{"虫", "むし", "insect"},
+	{"血", "ち", "blood"},
+	{"行", "ぎょうがまえ", "walk enclosure"},
+	{"衣", "ころも", "clothes"},
+	{"襾", "にし", "cover"},
+	{"見", "みる", "see"},
+	{"角", "つの", "horn"},
+	{"言", "げん", "speech"},
+	{"谷", "たに", "valley"},
+	{"豆", "まめ", "bean"},
+	{"豕", "いのこ", "pig"},
+	{"豸", "むじなへん", "badger"},
+	{"貝", "かい", "shell"},
+	{"赤", "あか", "red"},
+	{"走", "はしる", "run"},
+	{"足", "あし", "foot"},
+	{"身", "み", "body"},
+	{"車", "くるま", "cart"},
+	{"辛", "からい", "bitter"},
+	{"辰", "しんのたつ", "morning"},
+	{"辵", "しんにょう", "walk"},
+	{"邑", "おおざと", "city"},
+	{"酉", "ひよみのとり", "wine"},
+	{"釆", "のごめ", "distinguish"},
+	{"里", "さと", "village"},
+	{"金", "かね", "metal"},
+	{"長", "ながい", "long"},
+	{"門", "もん", "gate"},
+	{"阜", "こざとへん", "mound"},
+	{"隶", "れいづくり", "slave"},
+	{"隹", "ふるとり", "short-tailed bird"},
+	{"雨", "あめ", "rain"},
+	{"青", "あお", "blue"},
+	{"非", "あらず", "wrong"},
+	{"面", "めん", "face"},
+	{"革", "かくのかわ", "leather"},
+	{"韋", "なめしがわ", "tanned leather"},
+	{"韭", "にら", "leek"},
+	{"音", "おと", "sound"},
+	{"頁", "おおがい", "leaf"},
+	{"風", "かぜ", "wind"},
+	{"飛", "とぶ", "fly"},
+	{"食", "しょく", "eat"},
+	{"首", "くび", "head"},
+	{"香", "かおり", "fragrant"},
+	{"馬", "うま", "horse"},
+	{"骨", "ほね", "bone"},
+	{"高", "たかい", "tall"},
+	{"髟", "かみがしら", "hair"},
+	{"鬥", "とうがまえ", "fight"},
+	{"鬯", "ちょう", "sacrificial wine"},
+	{"鬲", "かなえ", "cauldron"},
+	{"鬼", "おに", "ghost"},
+	{"魚", "うお", "fish"},
+	{"鳥", "とり", "bird"},
+	{"鹵", "ろ", "salt"},
+	{"鹿", "しか", "deer"},
+	{"麥", "むぎ", "wheat"},
+	{"麻", "あさ", "hemp"},
+	{"黃", "き", "yellow"},
+	{"黍", "きび", "millet"},
+	{"黑", "くろ", "black"},
+	{"黹", "ふつ", "embroidery"},
+	{"黽", "べん", "frog"},
+	{"鼎", "かなえ", "tripod"},
+	{"鼓", "つづみ", "drum"},
+	{"鼠", "ねずみ", "rat"},
+	{"鼻", "はな", "nose"},
+	{"齊", "せい", "even"},
+	{"齒", "は", "tooth"},
+	{"龍", "りゅう", "dragon"},
+	{"龜", "かめ", "turtle"},
+	{"龠", "やく", "flute"},
+}
+
+// KRADFILE and RADKFILE write some components as a kanji which
+// contains them, since the components themselves are not in JIS X
+// 0208. These are replaced with the actual components.
+var kradSubstitutes = map[string]string{
+	"化": "亻",
+	"个": "𠆢",
+	"并": "丷",
+	"刈": "刂",
+	"込": "⻌",
+	"尚": "⺌",
+	"忙": "忄",
+	"扎": "扌",
+	"汁": "氵",
+	"犯": "犭",
+	"艾": "⺾",
+	"邦": "⻏",
+	"阡": "⻖",
+	"老": "⺹",
+	"杰": "灬",
+	"礼": "礻",
+	"疔": "疒",
+	"禹": "禸",
+	"初": "衤",
+	"買": "罒",
+}
+
+// Formats a classical radical number as the radical with its name and
+// meaning, e.g. "85" -> "85 水 (みず, water)".
+func kangxiRadicalDescription(number string) (string, bool) {
+	index, err := strconv.Atoi(number)
+	if err != nil || index < 1 || index > len(kangxiRadicals) {
+		return "", false
+	}
+	radical := kangxiRadicals[index-1]
+	return fmt.Sprintf("%d %s (%s, %s)", index, radical.character, radical.name, radical.meaning), true
+}
+
+// Reads an EDRDG text file, which is EUC-JP encoded unless it has been
+// converted to UTF-8.
+func readEdrdgTextFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if !utf8.Valid(data) {
+		return japanese.EUCJP.NewDecoder().Bytes(data)
+	}
+	return data, nil
+}
+
+func kradComponent(component string) string {
+	if substitute, ok := kradSubstitutes[component]; ok {
+		return substitute
+	}
+	return component
+}
+
+// Loads the components of each kanji from a KRADFILE, with lines
+// such as "亜 : ｜ 一 口".
+func loadKradfile(path string, components map[string][]string) error {
+	data, err := readEdrdgTextFile(path)
+	if err != nil {
+		return err
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		kanji, parts, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		kanji = strings.TrimSpace(kanji)
+		for _, component := range strings.Fields(parts) {
+			components[kanji] = appendStringUnique(components[kanji], kradComponent(component))
+		}
+	}
+
+	return scanner.Err()
+}
+
+// Loads a RADKFILE, which lists the kanji containing each component
+// under lines such as "$ 一 1" (the component and its stroke count),
+// and adds the components of kanji not found in a KRADFILE. The
+// components are ordered by stroke count, as in KRADFILE.
+func loadRadkfile(path string, components map[string][]string) error {
+	data, err := readEdrdgTextFile(path)
+	if err != nil {
+		return err
+	}
+
+	var (
+		known     = make(map[string]bool)
+		strokes   = make(map[string]int)
+		found     = make(map[string][]string)
+		component string
+	)
+
+	for kanji := range components {
+		known[kanji] = true
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		if strings.HasPrefix(line, "$") {
+			fields := strings.Fields(line[1:])
+			component = ""
+			if len(fields) >= 2 {
+				component = kradComponent(fields[0])
+				strokes[component], _ = strconv.Atoi(fields[1])
+			}
+			continue
+		}
+
+		if component == "" {
+			continue
+		}
+
+		for _, char := range strings.TrimSpace(line) {
+			if kanji := string(char); !known[kanji] {
+				found[kanji] = appendStringUnique(found[kanji], component)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	for kanji, parts := range found {
+		sort.SliceStable(parts, func(i, j int) bool {
+			return strokes[parts[i]] < strokes[parts[j]]
+		})
+		components[kanji] = parts
+	}
+
+	return nil
+}
+
+// Loads the components of each kanji from the given KRADFILEs and
+// RADKFILE, any of which may be omitted.
+func loadKanjiComponents(kradfiles []string, radkfile string) (map[string][]string, error) {
+	components := make(map[string][]string)
+
+	for _, path := range kradfiles {
+		if err := loadKradfile(path, components); err != nil {
+			return nil, err
+		}
+	}
+
+	if radkfile != "" {
+		if err := loadRadkfile(radkfile, components); err != nil {
+			return nil, err
+		}
+	}
+
+	return components, nil
+}
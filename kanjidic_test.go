@@ -0,0 +1,72 @@
+package yomichan
+
+import (
+	"reflect"
+	"testing"
+
+	"foosoft.net/projects/jmdict"
+)
+
+func TestKanjidicExtractReadings(t *testing.T) {
+	entry := jmdict.KanjidicCharacter{
+		Literal: "和",
+		ReadingMeaning: &jmdict.KanjidicReadingMeaning{
+			Readings: []jmdict.KanjidicReading{
+				{Value: "he2", Type: "pinyin"},
+				{Value: "hwa", Type: "korean_r"},
+				{Value: "ワ", Type: "ja_on"},
+			},
+			Nanori: []string{"かず", "やす"},
+		},
+	}
+
+	tests := []struct {
+		readingTypes []string
+		want         map[string]string
+	}{
+		{nil, map[string]string{"nanori": "かず, やす"}},
+		{[]string{"pinyin"}, map[string]string{"nanori": "かず, やす", "pinyin": "hé"}},
+		{[]string{"korean_r", "vietnam"}, map[string]string{"nanori": "かず, やす", "korean_r": "hwa"}},
+	}
+
+	for _, test := range tests {
+		kanji := dbKanji{Stats: make(map[string]string)}
+		kanjidicExtractReadings(&kanji, entry, test.readingTypes)
+		if !reflect.DeepEqual(kanji.Stats, test.want) {
+			t.Errorf("kanjidicExtractReadings(%q) = %v, want %v", test.readingTypes, kanji.Stats, test.want)
+		}
+	}
+
+	if _, err := kanjidicReadingTypes([]string{"nanori"}); err == nil {
+		t.Errorf("kanjidicReadingTypes accepted nanori, which is always included")
+	}
+}
+
+func TestKanjidicLanguages(t *testing.T) {
+	tests := []struct {
+		language string
+		want     []string
+		wantErr  bool
+	}{
+		{"", []string{"eng"}, false},
+		{"english", []string{"eng"}, false},
+		{"portuguese", []string{"por"}, false},
+		{"english, french,english", []string{"eng", "fre"}, false},
+		{"spanish,portuguese", []string{"spa", "por"}, false},
+		{"german", nil, true},
+		{"klingon", nil, true},
+	}
+
+	for _, test := range tests {
+		got, err := kanjidicLanguages(test.language)
+		if (err != nil) != test.wantErr || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("kanjidicLanguages(%q) = %q, %v, want %q (error %v)", test.language, got, err, test.want, test.wantErr)
+		}
+	}
+
+	for code, want := range map[string]string{"eng": "", "fre": "fr", "por": "pt", "spa": "es"} {
+		if tag := kanjidicMeaningTag(code); tag != want {
+			t.Errorf("kanjidicMeaningTag(%q) = %q, want %q", code, tag, want)
+		}
+	}
+}
@@ -0,0 +1,282 @@
+package yomichan
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const kanjivgAttribution = "This dictionary includes stroke order data from KanjiVG, copyright Ulrich Apel, released under the Creative Commons Attribution-Share Alike 3.0 license. See https://kanjivg.tagaini.net/"
+
+// Stroke colors cycled through when strokes are colored individually.
+var kanjivgColors = []string{
+	"#bf0000", "#bf5600", "#bfac00", "#7cbf00", "#26bf00", "#00bf2f",
+	"#00bf86", "#00a2bf", "#004cbf", "#0900bf", "#5f00bf", "#b500bf",
+	"#bf0072", "#bf001c",
+}
+
+// Returns the character encoded in a KanjiVG id suffix such as
+// "05e7d". Variant forms such as "05e7d-Kaisho" are not supported.
+func kanjivgCharacter(code string) (string, bool) {
+	codepoint, err := strconv.ParseInt(code, 16, 32)
+	if err != nil {
+		return "", false
+	}
+	return string(rune(codepoint)), true
+}
+
+// Reads the stroke paths of each character from either the combined
+// kanjivg.xml file or a single KanjiVG SVG file.
+func kanjivgParse(reader io.Reader, strokes map[string][]string) error {
+	decoder := xml.NewDecoder(reader)
+	decoder.Strict = false
+
+	var character string
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		element, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		var id, path string
+		for _, attr := range element.Attr {
+			switch attr.Name.Local {
+			case "id":
+				id = attr.Value
+			case "d":
+				path = attr.Value
+			}
+		}
+
+		switch element.Name.Local {
+		case "kanji":
+			character, _ = kanjivgCharacter(strings.TrimPrefix(id, "kvg:kanji_"))
+		case "g":
+			if code := strings.TrimPrefix(id, "kvg:StrokePaths_"); code != id {
+				character, _ = kanjivgCharacter(code)
+			}
+		case "path":
+			if character != "" && path != "" {
+				strokes[character] = append(strokes[character], path)
+			}
+		}
+	}
+}
+
+// Loads the stroke paths of each character from the combined KanjiVG
+// XML file or a directory of KanjiVG SVG files.
+func loadKanjiVG(path string) (map[string][]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if !entry.IsDir() && strings.EqualFold(filepath.Ext(name), ".svg") && !strings.Contains(name, "-") {
+				paths = append(paths, filepath.Join(path, name))
+			}
+		}
+	} else {
+		paths = []string{path}
+	}
+
+	strokes := make(map[string][]string)
+	for _, path := range paths {
+		fp, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		err = kanjivgParse(fp, strokes)
+		fp.Close()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+
+	return strokes, nil
+}
+
+// Returns the starting point of a stroke path, where its number is shown.
+// Coordinates may be separated by commas, spaces or the sign of the next
+// number, as in "M31.5-0.5".
+func kanjivgStrokeStart(path string) (float64, float64, bool) {
+	path = strings.TrimSpace(path)
+	if path == "" || path[0] != 'M' && path[0] != 'm' {
+		return 0, 0, false
+	}
+
+	var coords []float64
+	start := -1
+	for i := 1; i <= len(path) && len(coords) < 2; i++ {
+		var c byte
+		if i < len(path) {
+			c = path[i]
+		}
+
+		if c >= '0' && c <= '9' || c == '.' {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+
+		if start >= 0 {
+			value, err := strconv.ParseFloat(path[start:i], 64)
+			if err != nil {
+				return 0, 0, false
+			}
+			coords = append(coords, value)
+			start = -1
+		}
+
+		if c == '-' || c == '+' {
+			start = i
+		} else if c != ',' && c != ' ' && c != '\t' && c != '\r' && c != '\n' {
+			break
+		}
+	}
+
+	if len(coords) < 2 {
+		return 0, 0, false
+	}
+
+	return coords[0], coords[1], true
+}
+
+// Renders a stroke order diagram with each stroke numbered at its
+// starting point, using KanjiVG's 109x109 coordinate space.
+func kanjivgRenderSvg(strokes []string, colored bool) []byte {
+	var b strings.Builder
+	b.WriteString(`<svg xmlns="http://www.w3.org/2000/svg" width="109" height="109" viewBox="0 0 109 109">`)
+	b.WriteString(`<g style="fill:none;stroke:#000000;stroke-width:3;stroke-linecap:round;stroke-linejoin:round;">`)
+	for i, stroke := range strokes {
+		if colored {
+			fmt.Fprintf(&b, `<path d="%s" stroke="%s"/>`, html.EscapeString(stroke), kanjivgColors[i%len(kanjivgColors)])
+		} else {
+			fmt.Fprintf(&b, `<path d="%s"/>`, html.EscapeString(stroke))
+		}
+	}
+	b.WriteString(`</g>`)
+
+	b.WriteString(`<g style="font-size:8px;font-family:sans-serif;fill:#808080;">`)
+	for i, stroke := range strokes {
+		x, y, ok := kanjivgStrokeStart(stroke)
+		if !ok {
+			continue
+		}
+		fill := ""
+		if colored {
+			fill = fmt.Sprintf(` fill="%s"`, kanjivgColors[i%len(kanjivgColors)])
+		}
+		fmt.Fprintf(&b, `<text x="%.2f" y="%.2f"%s>%d</text>`, x-6, y-2, fill, i+1)
+	}
+	b.WriteString(`</g></svg>`)
+
+	return []byte(b.String())
+}
+
+func kanjivgMediaPath(character string) string {
+	return fmt.Sprintf("kanjivg/%05x.svg", []rune(character)[0])
+}
+
+// Builds term entries showing the stroke order diagram of each character,
+// along with the diagrams to bundle as media.
+func kanjivgTerms(strokes map[string][]string, characters []string, colored bool) (dbTermList, map[string][]byte) {
+	var (
+		terms dbTermList
+		media = make(map[string][]byte)
+	)
+
+	for _, character := range characters {
+		paths := strokes[character]
+		if len(paths) == 0 {
+			continue
+		}
+
+		mediaPath := kanjivgMediaPath(character)
+		media[mediaPath] = kanjivgRenderSvg(paths, colored)
+
+		image := map[string]any{
+			"tag":       "img",
+			"path":      mediaPath,
+			"width":     6,
+			"height":    6,
+			"sizeUnits": "em",
+			"title":     character,
+		}
+
+		count := fmt.Sprintf("%d strokes", len(paths))
+		if len(paths) == 1 {
+			count = "1 stroke"
+		}
+
+		terms = append(terms, dbTerm{
+			Expression: character,
+			Glossary:   []any{contentStructure(image, contentDiv(contentAttr{fontSize: "smaller"}, count))},
+		})
+	}
+
+	return terms, media
+}
+
+func kanjivgExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	strokes, err := loadKanjiVG(inputPath)
+	if err != nil {
+		return err
+	}
+
+	var characters []string
+	for character := range strokes {
+		characters = append(characters, character)
+	}
+	sort.Strings(characters)
+
+	terms, media := kanjivgTerms(strokes, characters, options.KanjiVGColors)
+
+	if title == "" {
+		title = "KanjiVG"
+	}
+
+	recordData := map[string]dbRecordList{
+		"term": terms.crush(),
+	}
+
+	index := dbIndex{
+		Title:       title,
+		Revision:    "kanjivg",
+		Sequenced:   false,
+		Attribution: kanjivgAttribution,
+	}
+
+	return writeDbWithMedia(
+		outputPath,
+		index,
+		recordData,
+		media,
+		stride,
+		pretty,
+	)
+}
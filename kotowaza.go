@@ -76,6 +76,7 @@ func (e *kotowazaExtractor) extractTerms(entry zig.BookEntry, sequence int) []db
 				Sequence:   sequence,
 			}
 
+			e.exportRules(&term, nil)
 			terms = append(terms, term)
 		}
 
@@ -88,13 +89,22 @@ func (e *kotowazaExtractor) extractKanji(entry zig.BookEntry) []dbKanji {
 	return nil
 }
 
+func (e *kotowazaExtractor) extractTermMeta(entry zig.BookEntry, terms []dbTerm) []dbMeta {
+	return nil
+}
+
 func (e *kotowazaExtractor) exportRules(term *dbTerm, tags []string) {
+	term.addRules(japaneseGrammarRules(term.Expression, tags)...)
 }
 
 func (*kotowazaExtractor) getRevision() string {
 	return "kotowaza1"
 }
 
+func (*kotowazaExtractor) getTags() dbTagList {
+	return nil
+}
+
 func (*kotowazaExtractor) getFontNarrow() map[int]string {
 	return map[int]string{}
 }
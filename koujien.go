@@ -12,8 +12,6 @@ type koujienExtractor struct {
 	readGroupExp *regexp.Regexp
 	expVarExp    *regexp.Regexp
 	metaExp      *regexp.Regexp
-	v5Exp        *regexp.Regexp
-	v1Exp        *regexp.Regexp
 }
 
 func makeKoujienExtractor() epwingExtractor {
@@ -22,8 +20,6 @@ func makeKoujienExtractor() epwingExtractor {
 		readGroupExp: regexp.MustCompile(`[‐・]+`),
 		expVarExp:    regexp.MustCompile(`\(([^\)]*)\)`),
 		metaExp:      regexp.MustCompile(`（([^）]*)）`),
-		v5Exp:        regexp.MustCompile(`(動.[四五](［[^］]+］)?)|(動..二)`),
-		v1Exp:        regexp.MustCompile(`(動..一)`),
 	}
 }
 func makeFuzokuExtractor() epwingExtractor {
@@ -32,8 +28,6 @@ func makeFuzokuExtractor() epwingExtractor {
 		readGroupExp: regexp.MustCompile(`[-・]+`),
 		expVarExp:    regexp.MustCompile(`\(([^\)]*)\)`),
 		metaExp:      regexp.MustCompile(`（([^）]*)）`),
-		v5Exp:        regexp.MustCompile(`(動.[四五](［[^］]+］)?)|(動..二)`),
-		v1Exp:        regexp.MustCompile(`(動..一)`),
 	}
 }
 
@@ -106,26 +100,23 @@ func (*koujienExtractor) extractKanji(entry zig.BookEntry) []dbKanji {
 	return nil
 }
 
+func (*koujienExtractor) extractTermMeta(entry zig.BookEntry, terms []dbTerm) []dbMeta {
+	return nil
+}
+
 func (e *koujienExtractor) exportRules(term *dbTerm, tags []string) {
-	for _, tag := range tags {
-		if tag == "形" {
-			term.addRules("adj-i")
-		} else if tag == "動サ変" && (strings.HasSuffix(term.Expression, "する") || strings.HasSuffix(term.Expression, "為る")) {
-			term.addRules("vs")
-		} else if term.Expression == "来る" {
-			term.addRules("vk")
-		} else if e.v5Exp.MatchString(tag) {
-			term.addRules("v5")
-		} else if e.v1Exp.MatchString(tag) {
-			term.addRules("v1")
-		}
-	}
+	term.addRules(japaneseGrammarRules(term.Expression, tags)...)
+	term.addDefinitionTags(japanesePartsOfSpeech(tags)...)
 }
 
 func (*koujienExtractor) getRevision() string {
 	return "koujien"
 }
 
+func (*koujienExtractor) getTags() dbTagList {
+	return japaneseGrammarTags()
+}
+
 func (*koujienExtractor) getFontNarrow() map[int]string {
 	return map[int]string{}
 }
@@ -0,0 +1,566 @@
+package yomichan
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+	"golang.org/x/text/encoding/unicode"
+)
+
+type mdictKey struct {
+	offset uint64
+	text   string
+}
+
+type mdictFile struct {
+	header  map[string]string
+	keys    []mdictKey
+	records []byte
+}
+
+type mdictReader struct {
+	data        []byte
+	position    int
+	numberWidth int
+	err         error
+}
+
+// Limits how many "@@@LINK=" redirects are followed from one record.
+const mdictMaxLinkDepth = 8
+
+var (
+	mdictHeaderAttrExp = regexp.MustCompile(`(\w+)="([^"]*)"`)
+)
+
+func (r *mdictReader) bytes(size uint64) []byte {
+	if r.err != nil {
+		return nil
+	}
+	if size > uint64(len(r.data)-r.position) {
+		r.err = errors.New("truncated MDict file")
+		return nil
+	}
+	data := r.data[r.position : r.position+int(size)]
+	r.position += int(size)
+	return data
+}
+
+func (r *mdictReader) number() uint64 {
+	data := r.bytes(uint64(r.numberWidth))
+	if data == nil {
+		return 0
+	}
+	if r.numberWidth == 8 {
+		return binary.BigEndian.Uint64(data)
+	}
+	return uint64(binary.BigEndian.Uint32(data))
+}
+
+func (r *mdictReader) uint8() uint64 {
+	if data := r.bytes(1); data != nil {
+		return uint64(data[0])
+	}
+	return 0
+}
+
+func (r *mdictReader) uint16() uint64 {
+	if data := r.bytes(2); data != nil {
+		return uint64(binary.BigEndian.Uint16(data))
+	}
+	return 0
+}
+
+func (r *mdictReader) uint32() uint64 {
+	if data := r.bytes(4); data != nil {
+		return uint64(binary.BigEndian.Uint32(data))
+	}
+	return 0
+}
+
+// Decompresses a key or record block, which starts with a compression
+// type and a checksum of the decompressed data.
+func mdictDecompressBlock(block []byte, size uint64) ([]byte, error) {
+	if len(block) < 8 {
+		return nil, errors.New("truncated MDict block")
+	}
+
+	data := block[8:]
+	switch binary.LittleEndian.Uint32(block) {
+	case 0:
+		return data, nil
+	case 1:
+		return lzo1xDecompress(data, int(size))
+	case 2:
+		reader, err := zlib.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return io.ReadAll(reader)
+	default:
+		return nil, fmt.Errorf("unsupported MDict block compression type %d", binary.LittleEndian.Uint32(block))
+	}
+}
+
+func mdictEncoding(name string, isMdd bool) encoding.Encoding {
+	if isMdd {
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	}
+
+	switch strings.ToUpper(name) {
+	case "UTF-16":
+		return unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)
+	case "GBK", "GB2312", "GB18030":
+		return simplifiedchinese.GB18030
+	case "BIG5":
+		return traditionalchinese.Big5
+	default:
+		return unicode.UTF8
+	}
+}
+
+func mdictParseHeader(r *mdictReader) (map[string]string, error) {
+	size := r.uint32()
+	data := r.bytes(size)
+	r.bytes(4) // checksum
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	text, err := unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM).NewDecoder().Bytes(data)
+	if err != nil {
+		return nil, err
+	}
+
+	header := make(map[string]string)
+	for _, matches := range mdictHeaderAttrExp.FindAllStringSubmatch(string(text), -1) {
+		header[matches[1]] = html.UnescapeString(matches[2])
+	}
+
+	return header, nil
+}
+
+// Parses the key section of an MDict file. The key block index lists
+// the sizes of each key block, and each key block holds the record
+// offsets of its keys.
+func mdictParseKeys(r *mdictReader, version float64, encrypted int, decoder *encoding.Decoder, isUtf16 bool) ([]mdictKey, error) {
+	var blockCount, keyIndexSize, keyBlocksSize uint64
+	if version >= 2.0 {
+		blockCount = r.number()
+		r.number() // entry count
+		r.number() // decompressed key index size
+		keyIndexSize = r.number()
+		keyBlocksSize = r.number()
+		r.bytes(4) // checksum
+	} else {
+		blockCount = r.number()
+		r.number() // entry count
+		keyIndexSize = r.number()
+		keyBlocksSize = r.number()
+	}
+
+	keyIndex := r.bytes(keyIndexSize)
+	keyBlocks := r.bytes(keyBlocksSize)
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	if version >= 2.0 {
+		if encrypted&2 != 0 {
+			keyIndex = mdictDecryptKeyIndex(keyIndex)
+		}
+
+		var err error
+		if keyIndex, err = mdictDecompressBlock(keyIndex, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	type blockSize struct {
+		compressed   uint64
+		decompressed uint64
+	}
+
+	var (
+		sizes       []blockSize
+		indexReader = mdictReader{data: keyIndex, numberWidth: r.numberWidth}
+	)
+
+	skipText := func(size uint64) {
+		if version >= 2.0 {
+			size++ // null terminator
+		}
+		if isUtf16 {
+			size *= 2
+		}
+		indexReader.bytes(size)
+	}
+
+	for i := uint64(0); i < blockCount; i++ {
+		indexReader.number() // entry count
+		if version >= 2.0 {
+			skipText(indexReader.uint16())
+			skipText(indexReader.uint16())
+		} else {
+			skipText(indexReader.uint8())
+			skipText(indexReader.uint8())
+		}
+		sizes = append(sizes, blockSize{indexReader.number(), indexReader.number()})
+	}
+
+	if indexReader.err != nil {
+		return nil, indexReader.err
+	}
+
+	terminator := []byte{0}
+	if isUtf16 {
+		terminator = []byte{0, 0}
+	}
+
+	var (
+		keys        []mdictKey
+		blockReader = mdictReader{data: keyBlocks, numberWidth: r.numberWidth}
+	)
+
+	for _, size := range sizes {
+		block, err := mdictDecompressBlock(blockReader.bytes(size.compressed), size.decompressed)
+		if err != nil {
+			return nil, err
+		}
+
+		for len(block) > r.numberWidth {
+			key := mdictKey{}
+			if r.numberWidth == 8 {
+				key.offset = binary.BigEndian.Uint64(block)
+			} else {
+				key.offset = uint64(binary.BigEndian.Uint32(block))
+			}
+			block = block[r.numberWidth:]
+
+			end := 0
+			for end < len(block) && !bytes.HasPrefix(block[end:], terminator) {
+				end += len(terminator)
+			}
+
+			text, err := decoder.Bytes(block[:end])
+			if err != nil {
+				return nil, err
+			}
+			key.text = string(text)
+			keys = append(keys, key)
+
+			if end+len(terminator) > len(block) {
+				break
+			}
+			block = block[end+len(terminator):]
+		}
+	}
+
+	return keys, blockReader.err
+}
+
+func mdictParseRecords(r *mdictReader) ([]byte, error) {
+	blockCount := r.number()
+	r.number() // entry count
+	r.number() // record index size
+	r.number() // record blocks size
+
+	type blockSize struct {
+		compressed   uint64
+		decompressed uint64
+	}
+
+	var sizes []blockSize
+	for i := uint64(0); i < blockCount; i++ {
+		sizes = append(sizes, blockSize{r.number(), r.number()})
+	}
+
+	var records []byte
+	for _, size := range sizes {
+		block, err := mdictDecompressBlock(r.bytes(size.compressed), size.decompressed)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, block...)
+	}
+
+	return records, r.err
+}
+
+func mdictLoad(path string, isMdd bool) (*mdictFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &mdictReader{data: data}
+
+	header, err := mdictParseHeader(r)
+	if err != nil {
+		return nil, err
+	}
+
+	// version 3.0 files use a different layout which is not supported
+	version, _ := strconv.ParseFloat(header["GeneratedByEngineVersion"], 64)
+	if version >= 3.0 {
+		return nil, fmt.Errorf("MDict version %s files are not supported", header["GeneratedByEngineVersion"])
+	}
+
+	r.numberWidth = 4
+	if version >= 2.0 {
+		r.numberWidth = 8
+	}
+
+	encrypted := 0
+	switch value := header["Encrypted"]; value {
+	case "", "No":
+	case "Yes":
+		encrypted = 1
+	default:
+		encrypted, _ = strconv.Atoi(value)
+	}
+
+	if encrypted&1 != 0 {
+		return nil, errors.New("MDict files with encrypted key headers require a registration code and are not supported")
+	}
+
+	textEncoding := mdictEncoding(header["Encoding"], isMdd)
+	isUtf16 := isMdd || strings.EqualFold(header["Encoding"], "UTF-16")
+
+	keys, err := mdictParseKeys(r, version, encrypted, textEncoding.NewDecoder(), isUtf16)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := mdictParseRecords(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &mdictFile{header: header, keys: keys, records: records}, nil
+}
+
+// Returns the raw record data for the key at the given index.
+func (f *mdictFile) record(index int) []byte {
+	start := f.keys[index].offset
+	end := uint64(len(f.records))
+	if index+1 < len(f.keys) {
+		end = f.keys[index+1].offset
+	}
+	if start > end || end > uint64(len(f.records)) {
+		return nil
+	}
+	return f.records[start:end]
+}
+
+func (f *mdictFile) recordText(index int) string {
+	return strings.TrimRight(string(f.record(index)), "\x00\r\n")
+}
+
+// Returns the indices of the records holding the definitions of each
+// key, following "@@@LINK=" redirects to the headwords they name.
+// Chains longer than mdictMaxLinkDepth are assumed to loop and are
+// dropped along with links to missing headwords.
+func (f *mdictFile) resolveLinks() [][]int {
+	// a headword may have several records
+	keyRecords := make(map[string][]int)
+	for i, key := range f.keys {
+		expression := strings.TrimSpace(key.text)
+		keyRecords[expression] = append(keyRecords[expression], i)
+	}
+
+	var resolve func(index, depth int) []int
+	resolve = func(index, depth int) []int {
+		target, ok := mdictLinkTarget(f.recordText(index))
+		if !ok {
+			return []int{index}
+		}
+		if depth >= mdictMaxLinkDepth {
+			return nil
+		}
+		var results []int
+		for _, targetIndex := range keyRecords[target] {
+			results = append(results, resolve(targetIndex, depth+1)...)
+		}
+		return results
+	}
+
+	definitions := make([][]int, len(f.keys))
+	for i := range f.keys {
+		definitions[i] = resolve(i, 0)
+	}
+	return definitions
+}
+
+// Normalizes a resource path so that keys from an .mdd file (e.g.
+// "\img\a.png") and references from records (e.g. "img/a.png") match.
+func mdictResourcePath(src string) string {
+	src = strings.TrimPrefix(src, "file://")
+	src = strings.ReplaceAll(src, "\\", "/")
+	return strings.ToLower(strings.TrimLeft(path.Clean("/"+src), "/"))
+}
+
+// Loads the resources of the .mdd files accompanying an .mdx file.
+// Large dictionaries split their resources across several numbered
+// files, e.g. "dict.mdd", "dict.1.mdd" and "dict.2.mdd".
+func mdictLoadResources(inputPath string) (map[string][]byte, error) {
+	basePath := strings.TrimSuffix(inputPath, filepath.Ext(inputPath))
+	resources := make(map[string][]byte)
+
+	for i := 0; ; i++ {
+		mddPath := basePath + ".mdd"
+		if i > 0 {
+			mddPath = fmt.Sprintf("%s.%d.mdd", basePath, i)
+		}
+
+		if _, err := os.Stat(mddPath); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+
+		mdd, err := mdictLoad(mddPath, true)
+		if err != nil {
+			return nil, err
+		}
+
+		for j, key := range mdd.keys {
+			resources[mdictResourcePath(key.text)] = mdd.record(j)
+		}
+	}
+
+	return resources, nil
+}
+
+// Returns the headword a record redirects to with "@@@LINK=".
+func mdictLinkTarget(text string) (string, bool) {
+	if target, ok := strings.CutPrefix(text, "@@@LINK="); ok {
+		target = strings.TrimSpace(target)
+		return target, target != ""
+	}
+	return "", false
+}
+
+func mdictLinkQuery(href string) (string, bool) {
+	for _, prefix := range []string{"entry://", "bword://"} {
+		if strings.HasPrefix(href, prefix) {
+			query, _, _ := strings.Cut(strings.TrimPrefix(href, prefix), "#")
+			return query, query != ""
+		}
+	}
+	return "", false
+}
+
+func mdictExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	mdx, err := mdictLoad(inputPath, false)
+	if err != nil {
+		return err
+	}
+
+	resources, err := mdictLoadResources(inputPath)
+	if err != nil {
+		return err
+	}
+
+	media := make(map[string][]byte)
+	addMedia := func(src string) (string, bool) {
+		resourcePath := mdictResourcePath(src)
+		data, ok := resources[resourcePath]
+		if !ok {
+			return "", false
+		}
+		mediaPath := "mdict/" + resourcePath
+		media[mediaPath] = data
+		return mediaPath, true
+	}
+
+	converter := markupConverter{
+		resolveLink: mdictLinkQuery,
+		resolveImage: func(src string) (any, bool) {
+			if mediaPath, ok := addMedia(src); ok {
+				return map[string]any{"tag": "img", "path": mediaPath}, true
+			}
+			return nil, false
+		},
+	}
+
+	isHtml := !strings.EqualFold(mdx.header["Format"], "Text")
+
+	glossaries := make(map[int]any)
+	recordGlossary := func(index int) (any, bool) {
+		if glossary, ok := glossaries[index]; ok {
+			return glossary, glossary != nil
+		}
+
+		var glossary any
+		if text := mdx.recordText(index); isHtml {
+			if contents := converter.htmlContent(text); len(contents) > 0 {
+				glossary = contentStructure(contents...)
+			}
+		} else if text != "" {
+			glossary = text
+		}
+
+		glossaries[index] = glossary
+		return glossary, glossary != nil
+	}
+
+	definitions := mdx.resolveLinks()
+
+	var terms dbTermList
+	for i, key := range mdx.keys {
+		term := dbTerm{
+			Expression: strings.TrimSpace(key.text),
+			Sequence:   i + 1,
+		}
+
+		for _, index := range definitions[i] {
+			if glossary, ok := recordGlossary(index); ok {
+				term.Glossary = append(term.Glossary, glossary)
+			}
+		}
+
+		if term.Expression != "" && len(term.Glossary) > 0 {
+			terms = append(terms, term)
+		}
+	}
+
+	if title == "" {
+		title = mdx.header["Title"]
+		if title == "" || strings.HasPrefix(title, "Title (No HTML code allowed)") {
+			title = strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+		}
+	}
+
+	recordData := map[string]dbRecordList{
+		"term": terms.crush(),
+	}
+
+	index := dbIndex{
+		Title:       title,
+		Revision:    "mdict",
+		Sequenced:   true,
+		Description: strings.TrimSpace(parseMarkup(mdx.header["Description"]).text()),
+	}
+
+	return writeDbWithMedia(
+		outputPath,
+		index,
+		recordData,
+		media,
+		stride,
+		pretty,
+	)
+}
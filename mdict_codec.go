@@ -0,0 +1,261 @@
+package yomichan
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/bits"
+)
+
+var errMdictLzo = errors.New("corrupt LZO compressed block")
+
+// Decompresses LZO1X compressed data, as used by some MDict blocks.
+// This is a port of lzo1x_decompress_safe from the LZO library.
+func lzo1xDecompress(in []byte, size int) ([]byte, error) {
+	const (
+		stateLiteralRun = iota
+		stateFirstLiteralRun
+		stateMatch
+		stateMatchDone
+		stateMatchNext
+	)
+
+	var (
+		out   = make([]byte, 0, size)
+		ip    int
+		t     int
+		state = stateLiteralRun
+		err   error
+	)
+
+	next := func() int {
+		if ip >= len(in) {
+			err = errMdictLzo
+			return 0
+		}
+		ip++
+		return int(in[ip-1])
+	}
+
+	// reads a run length encoded as a sequence of zero bytes, each
+	// worth 255, followed by a final non-zero byte
+	extended := func() int {
+		length := 0
+		for ip < len(in) && in[ip] == 0 {
+			length += 255
+			ip++
+		}
+		return length + next()
+	}
+
+	le16 := func() int {
+		return next() | next()<<8
+	}
+
+	copyLiterals := func(length int) {
+		if ip+length > len(in) {
+			err = errMdictLzo
+			return
+		}
+		out = append(out, in[ip:ip+length]...)
+		ip += length
+	}
+
+	copyMatch := func(position, length int) {
+		if position < 0 || position >= len(out) {
+			err = errMdictLzo
+			return
+		}
+		// matches may overlap the output being written
+		for i := 0; i < length; i++ {
+			out = append(out, out[position+i])
+		}
+	}
+
+	if len(in) > 0 && in[0] > 17 {
+		ip = 1
+		t = int(in[0]) - 17
+		if t < 4 {
+			state = stateMatchNext
+		} else {
+			copyLiterals(t)
+			state = stateFirstLiteralRun
+		}
+	}
+
+	for err == nil {
+		switch state {
+		case stateLiteralRun:
+			if t = next(); t >= 16 {
+				state = stateMatch
+				continue
+			}
+			if t == 0 {
+				t = 15 + extended()
+			}
+			copyLiterals(t + 3)
+			state = stateFirstLiteralRun
+		case stateFirstLiteralRun:
+			if t = next(); t >= 16 {
+				state = stateMatch
+				continue
+			}
+			position := len(out) - (1 + 0x0800) - t>>2 - next()<<2
+			copyMatch(position, 3)
+			state = stateMatchDone
+		case stateMatch:
+			var position, length int
+			switch {
+			case t >= 64:
+				position = len(out) - 1 - (t>>2)&7 - next()<<3
+				length = t>>5 + 1
+			case t >= 32:
+				if t &= 31; t == 0 {
+					t = 31 + extended()
+				}
+				position = len(out) - 1 - le16()>>2
+				length = t + 2
+			case t >= 16:
+				position = len(out) - (t&8)<<11
+				if t &= 7; t == 0 {
+					t = 7 + extended()
+				}
+				position -= le16() >> 2
+				if position == len(out) {
+					// end of stream marker
+					return out, err
+				}
+				position -= 0x4000
+				length = t + 2
+			default:
+				position = len(out) - 1 - t>>2 - next()<<2
+				length = 2
+			}
+			copyMatch(position, length)
+			state = stateMatchDone
+		case stateMatchDone:
+			if t = int(in[ip-2]) & 3; t == 0 {
+				state = stateLiteralRun
+			} else {
+				state = stateMatchNext
+			}
+		case stateMatchNext:
+			copyLiterals(t)
+			t = next()
+			state = stateMatch
+		}
+	}
+
+	return nil, err
+}
+
+var (
+	ripemd128LeftWords = [64]int{
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15,
+		7, 4, 13, 1, 10, 6, 15, 3, 12, 0, 9, 5, 2, 14, 11, 8,
+		3, 10, 14, 4, 9, 15, 8, 1, 2, 7, 0, 6, 13, 11, 5, 12,
+		1, 9, 11, 10, 0, 8, 12, 4, 13, 3, 7, 15, 14, 5, 6, 2,
+	}
+	ripemd128RightWords = [64]int{
+		5, 14, 7, 0, 9, 2, 11, 4, 13, 6, 15, 8, 1, 10, 3, 12,
+		6, 11, 3, 7, 0, 13, 5, 10, 14, 15, 8, 12, 4, 9, 1, 2,
+		15, 5, 1, 3, 7, 14, 6, 9, 11, 8, 12, 2, 10, 0, 4, 13,
+		8, 6, 4, 1, 3, 11, 15, 0, 5, 12, 2, 13, 9, 7, 10, 14,
+	}
+	ripemd128LeftShifts = [64]int{
+		11, 14, 15, 12, 5, 8, 7, 9, 11, 13, 14, 15, 6, 7, 9, 8,
+		7, 6, 8, 13, 11, 9, 7, 15, 7, 12, 15, 9, 11, 7, 13, 12,
+		11, 13, 6, 7, 14, 9, 13, 15, 14, 8, 13, 6, 5, 12, 7, 5,
+		11, 12, 14, 15, 14, 15, 9, 8, 9, 14, 5, 6, 8, 6, 5, 12,
+	}
+	ripemd128RightShifts = [64]int{
+		8, 9, 9, 11, 13, 15, 15, 5, 7, 7, 8, 11, 14, 14, 12, 6,
+		9, 13, 15, 7, 12, 8, 9, 11, 7, 7, 12, 7, 6, 15, 13, 11,
+		9, 7, 15, 11, 8, 6, 6, 14, 12, 13, 5, 14, 13, 13, 7, 5,
+		15, 5, 8, 11, 14, 14, 6, 14, 6, 9, 12, 9, 12, 5, 15, 8,
+	}
+	ripemd128LeftConstants  = [4]uint32{0x00000000, 0x5a827999, 0x6ed9eba1, 0x8f1bbcdc}
+	ripemd128RightConstants = [4]uint32{0x50a28be6, 0x5c4dd124, 0x6d703ef3, 0x00000000}
+)
+
+func ripemd128Function(round int, x, y, z uint32) uint32 {
+	switch round {
+	case 0:
+		return x ^ y ^ z
+	case 1:
+		return x&y | ^x&z
+	case 2:
+		return (x | ^y) ^ z
+	default:
+		return x&z | y&^z
+	}
+}
+
+// Computes the RIPEMD-128 digest of the data, which MDict uses to
+// derive the key for its encrypted key block index.
+func ripemd128(data []byte) []byte {
+	h := [4]uint32{0x67452301, 0xefcdab89, 0x98badcfe, 0x10325476}
+
+	padded := append([]byte{}, data...)
+	padded = append(padded, 0x80)
+	for len(padded)%64 != 56 {
+		padded = append(padded, 0)
+	}
+	length := make([]byte, 8)
+	binary.LittleEndian.PutUint64(length, uint64(len(data))*8)
+	padded = append(padded, length...)
+
+	for block := 0; block < len(padded); block += 64 {
+		var x [16]uint32
+		for i := range x {
+			x[i] = binary.LittleEndian.Uint32(padded[block+i*4:])
+		}
+
+		al, bl, cl, dl := h[0], h[1], h[2], h[3]
+		ar, br, cr, dr := h[0], h[1], h[2], h[3]
+
+		for j := 0; j < 64; j++ {
+			round := j / 16
+
+			t := bits.RotateLeft32(al+ripemd128Function(round, bl, cl, dl)+x[ripemd128LeftWords[j]]+ripemd128LeftConstants[round], ripemd128LeftShifts[j])
+			al, dl, cl, bl = dl, cl, bl, t
+
+			t = bits.RotateLeft32(ar+ripemd128Function(3-round, br, cr, dr)+x[ripemd128RightWords[j]]+ripemd128RightConstants[round], ripemd128RightShifts[j])
+			ar, dr, cr, br = dr, cr, br, t
+		}
+
+		t := h[1] + cl + dr
+		h[1] = h[2] + dl + ar
+		h[2] = h[3] + al + br
+		h[3] = h[0] + bl + cr
+		h[0] = t
+	}
+
+	digest := make([]byte, 16)
+	for i, value := range h {
+		binary.LittleEndian.PutUint32(digest[i*4:], value)
+	}
+
+	return digest
+}
+
+// Decrypts the key block index of an MDict file whose Encrypted
+// header attribute has the second bit set. The key is derived from the
+// checksum which precedes the encrypted data.
+func mdictDecryptKeyIndex(block []byte) []byte {
+	if len(block) < 8 {
+		return block
+	}
+
+	key := ripemd128(append(append([]byte{}, block[4:8]...), 0x95, 0x36, 0x00, 0x00))
+
+	decrypted := append([]byte{}, block...)
+	previous := byte(0x36)
+	for i := 8; i < len(block); i++ {
+		value := block[i]
+		value = value>>4 | value<<4
+		decrypted[i] = value ^ previous ^ byte(i-8) ^ key[(i-8)%len(key)]
+		previous = block[i]
+	}
+
+	return decrypted
+}
@@ -0,0 +1,62 @@
+package yomichan
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestRipemd128(t *testing.T) {
+	tests := []struct {
+		data   string
+		digest string
+	}{
+		{"", "cdf26213a150dc3ecb610f18f6b38b46"},
+		{"a", "86be7afa339d0fc7cfc785e72f578d33"},
+		{"abc", "c14a12199c66e4ba84636b0f69144c77"},
+		{"message digest", "9e327b3d6e523062afc1132d7df9d1b8"},
+		{"abcdefghijklmnopqrstuvwxyz", "fd2aa607f71dc8f510714922b371834e"},
+		{strings.Repeat("1234567890", 8), "3f45ef194732c2dbb2c4a2c769795fa3"},
+	}
+
+	for _, test := range tests {
+		if digest := hex.EncodeToString(ripemd128([]byte(test.data))); digest != test.digest {
+			t.Errorf("ripemd128(%q) = %s, want %s", test.data, digest, test.digest)
+		}
+	}
+}
+
+func TestLzo1xDecompress(t *testing.T) {
+	tests := []struct {
+		name  string
+		block []byte
+		want  []byte
+	}{
+		{
+			// literal run, M2 match with trailing literals, end marker
+			name:  "short match",
+			block: []byte{0x15, 'a', 'b', 'c', 'd', 0xef, 0x00, 'X', 'Y', 'Z', 0x11, 0x00, 0x00},
+			want:  []byte("abcdabcdabcdXYZ"),
+		},
+		{
+			// short initial literal run, M3 match with an extended length
+			name:  "long match",
+			block: []byte{0x12, 'a', 0x20, 0x07, 0x00, 0x00, 0x11, 0x00, 0x00},
+			want:  bytes.Repeat([]byte("a"), 41),
+		},
+	}
+
+	for _, test := range tests {
+		got, err := lzo1xDecompress(test.block, len(test.want))
+		if err != nil {
+			t.Errorf("%s: lzo1xDecompress returned %v", test.name, err)
+		} else if !bytes.Equal(got, test.want) {
+			t.Errorf("%s: lzo1xDecompress = %q, want %q", test.name, got, test.want)
+		}
+
+		if _, err := lzo1xDecompress(test.block[:len(test.block)-3], len(test.want)); err == nil {
+			t.Errorf("%s: lzo1xDecompress accepted a truncated block", test.name)
+		}
+	}
+}
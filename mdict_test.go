@@ -0,0 +1,26 @@
+package yomichan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMdictResolveLinks(t *testing.T) {
+	var mdx mdictFile
+	for _, entry := range []struct{ key, record string }{
+		{"colour", "@@@LINK=color\r\n\x00"},
+		{"color", "<b>color</b>\r\n\x00"},
+		{"colors", "@@@LINK=colour\r\n\x00"},
+		{"missing", "@@@LINK=nowhere\r\n\x00"},
+		{"loop", "@@@LINK=loop\r\n\x00"},
+		{"color", "second definition\x00"},
+	} {
+		mdx.keys = append(mdx.keys, mdictKey{offset: uint64(len(mdx.records)), text: entry.key})
+		mdx.records = append(mdx.records, entry.record...)
+	}
+
+	want := [][]int{{1, 5}, {1}, {1, 5}, nil, nil, {5}}
+	if got := mdx.resolveLinks(); !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveLinks() = %v, want %v", got, want)
+	}
+}
@@ -137,67 +137,23 @@ func (e *meikyouExtractor) extractKanji(entry zig.BookEntry) []dbKanji {
 	return nil
 }
 
-func (e *meikyouExtractor) exportRules(term *dbTerm, tags []string) {
-	for _, tag := range tags {
-		if tag == "名" {
-			term.addRules("n")
-		} else if tag == "代" {
-			term.addRules("pn")
-		} else if tag == "連体" {
-			term.addRules("adj-pn")
-		} else if tag == "副" {
-			term.addRules("adv")
-		} else if tag == "副ト" || tag == "副トニ" || tag == "トニ" {
-			term.addRules("adv-to")
-		} else if tag == "副助" {
-			term.addRules("adv")
-			term.addRules("prt")
-		} else if tag == "格助" || tag == "終助" {
-			term.addRules("prt")
-		} else if tag == "接" {
-			term.addRules("conj")
-		} else if tag == "接助" {
-			term.addRules("conj")
-			term.addRules("prt")
-		} else if tag == "接尾" {
-			term.addRules("suf")
-		} else if tag == "接頭" {
-			term.addRules("pref")
-		} else if tag == "補形" {
-			term.addRules("aux-adj")
-		} else if strings.HasPrefix(tag, "助動") || strings.HasPrefix(tag, "補動") {
-			term.addRules("aux-v")
-		} else if tag == "形動トタル" {
-			term.addRules("adj-t")
-			term.addRules("adv-to")
-		} else if tag == "形" {
-			term.addRules("adj-i")
-		} else if tag == "形動" {
-			term.addRules("adj-na")
-		}
+func (e *meikyouExtractor) extractTermMeta(entry zig.BookEntry, terms []dbTerm) []dbMeta {
+	return nil
+}
 
-		if strings.Contains(tag, "他") {
-			term.addRules("vt")
-		}
-		if strings.Contains(tag, "自") {
-			term.addRules("vi")
-		}
-		if strings.Contains(tag, "一") {
-			term.addRules("v1")
-		} else if strings.ContainsAny(tag, "二四五") && tag != "二" && tag != " トニ" {
-			term.addRules("v5")
-		} else if strings.Contains(tag, "サ変") && (strings.HasSuffix(term.Expression, "する") || strings.HasSuffix(term.Expression, "為る")) {
-			term.addRules("vs")
-		} else if term.Expression == "来る" {
-			term.addRules("vk")
-		}
-	}
+func (e *meikyouExtractor) exportRules(term *dbTerm, tags []string) {
+	term.addRules(japaneseGrammarRules(term.Expression, tags)...)
+	term.addDefinitionTags(japanesePartsOfSpeech(tags)...)
 }
 
 func (*meikyouExtractor) getRevision() string {
 	return "meikyou1"
 }
 
+func (*meikyouExtractor) getTags() dbTagList {
+	return japaneseGrammarTags()
+}
+
 func (*meikyouExtractor) getFontNarrow() map[int]string {
 	return map[int]string{
 		41249: " ",
@@ -0,0 +1,325 @@
+package yomichan
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	pitchNasalMark   = '゚' // combining semi-voiced sound mark, e.g. か゚
+	pitchDevoiceMark = '̥' // combining ring below, e.g. く̥
+)
+
+type pitchAccent struct {
+	Position int      `json:"position"`
+	Nasal    []int    `json:"nasal,omitempty"`
+	Devoice  []int    `json:"devoice,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+type pitchAccentData struct {
+	Reading string        `json:"reading"`
+	Pitches []pitchAccent `json:"pitches"`
+}
+
+var (
+	pitchPositionsExp     = regexp.MustCompile(`[\[［]([0-9０-９]+(?:[,，・][0-9０-９]+)*)[\]］]`)
+	pitchPositionsSepExp  = regexp.MustCompile(`[,，・]`)
+	pitchListSeparatorExp = regexp.MustCompile(`[,，・;；]`)
+	pitchListPosExp       = regexp.MustCompile(`^[(（]([^)）]+)[)）]`)
+)
+
+// Abbreviated parts of speech used by accent lists such as Kanjium,
+// e.g. "(名)0,(副)1", and the JMdict tags they are written as.
+var pitchPartOfSpeechTags = map[string]string{
+	"名":  "n",
+	"代":  "pn",
+	"副":  "adv",
+	"動":  "v",
+	"形":  "adj-i",
+	"形動": "adj-na",
+	"連体": "adj-pn",
+	"接":  "conj",
+	"感":  "int",
+	"助":  "prt",
+	"接頭": "pref",
+	"接尾": "suf",
+	"助数": "ctr",
+}
+
+// Returns true for small kana which combine with the preceding
+// character to form a single mora, e.g. "ゃ" in "しゃ".
+func isSmallKana(char rune) bool {
+	return strings.ContainsRune("ぁぃぅぇぉゃゅょゎァィゥェォャュョヮ", char)
+}
+
+// Splits a kana reading into morae, dropping any nasal or devoiced
+// markers. Returns the cleaned reading along with the 1-based mora
+// positions which were marked as nasal or devoiced.
+// E.g. "か゚っこう" -> ["か", "っ", "こ", "う"], [1], []
+func pitchMorae(reading string) (morae []string, nasal []int, devoice []int) {
+	for _, char := range reading {
+		switch {
+		case char == pitchNasalMark || char == '゜':
+			if len(morae) > 0 {
+				nasal = append(nasal, len(morae))
+			}
+		case char == pitchDevoiceMark:
+			if len(morae) > 0 {
+				devoice = append(devoice, len(morae))
+			}
+		case isSmallKana(char) && len(morae) > 0:
+			morae[len(morae)-1] += string(char)
+		default:
+			morae = append(morae, string(char))
+		}
+	}
+	return morae, nasal, devoice
+}
+
+// Parses a downstep number which may be written with either ASCII or
+// fullwidth digits.
+func parsePitchPosition(text string) (int, bool) {
+	if len(text) == 0 {
+		return 0, false
+	}
+	position := 0
+	for _, char := range text {
+		if !unicode.IsDigit(char) {
+			return 0, false
+		}
+		if char >= '０' && char <= '９' {
+			char = char - '０' + '0'
+		}
+		position = position*10 + int(char-'0')
+	}
+	return position, true
+}
+
+// Extracts the first group of bracketed accent numbers from the
+// text, e.g. "あい [1]【愛】" -> [1] or "［０・２］" -> [0, 2].
+func findPitchPositions(text string) []int {
+	matches := pitchPositionsExp.FindStringSubmatch(text)
+	if matches == nil {
+		return nil
+	}
+	var positions []int
+	for _, split := range pitchPositionsSepExp.Split(matches[1], -1) {
+		if position, ok := parsePitchPosition(split); ok {
+			positions = append(positions, position)
+		}
+	}
+	return positions
+}
+
+// Builds a "pitch" term_meta record. Nasal and devoiced markers in
+// the reading are stripped and applied to every pitch accent.
+func makePitchMeta(expression string, reading string, positions []int, tags []string) (dbMeta, bool) {
+	morae, nasal, devoice := pitchMorae(reading)
+	if len(morae) == 0 || len(positions) == 0 {
+		return dbMeta{}, false
+	}
+	for _, mora := range morae {
+		for _, char := range mora {
+			if char < 'ぁ' || char > 'ヿ' {
+				return dbMeta{}, false
+			}
+		}
+	}
+
+	data := pitchAccentData{Reading: strings.Join(morae, "")}
+	for _, position := range positions {
+		if position > len(morae) {
+			continue
+		}
+		data.Pitches = append(data.Pitches, pitchAccent{
+			Position: position,
+			Nasal:    nasal,
+			Devoice:  devoice,
+			Tags:     tags,
+		})
+	}
+	if len(data.Pitches) == 0 {
+		return dbMeta{}, false
+	}
+
+	if expression == "" {
+		expression = data.Reading
+	}
+
+	return dbMeta{expression, "pitch", data}, true
+}
+
+// Builds pitch records for each term extracted from an EPWING entry
+// using the accent numbers found in the given text.
+func epwingPitchMeta(terms []dbTerm, text string) []dbMeta {
+	positions := findPitchPositions(text)
+	if len(positions) == 0 {
+		return nil
+	}
+
+	var metas []dbMeta
+	for _, term := range terms {
+		reading := term.Reading
+		if reading == "" {
+			reading = term.Expression
+		}
+		if meta, ok := makePitchMeta(term.Expression, reading, positions, nil); ok {
+			metas = append(metas, meta)
+		}
+	}
+	return metas
+}
+
+// Splits the accent column of an accent list at its separators, except
+// within parentheses, where "・" separates parts of speech as in
+// "(名・副)0".
+func splitPitchList(text string) []string {
+	var (
+		parts []string
+		start int
+		depth int
+	)
+	for i, char := range text {
+		switch {
+		case char == '(' || char == '（':
+			depth++
+		case (char == ')' || char == '）') && depth > 0:
+			depth--
+		case depth == 0 && pitchListSeparatorExp.MatchString(string(char)):
+			parts = append(parts, text[start:i])
+			start = i + utf8.RuneLen(char)
+		}
+	}
+	return append(parts, text[start:])
+}
+
+// Parses the accent column of an accent list, e.g. "0,2" or
+// "(名)0,(副)1". Parts of speech apply to the position they precede.
+func parsePitchList(text string) (positions []int, tags [][]string) {
+	for _, part := range splitPitchList(text) {
+		part = strings.TrimSpace(part)
+
+		var partTags []string
+		if matches := pitchListPosExp.FindStringSubmatch(part); matches != nil {
+			for _, name := range strings.Split(matches[1], "・") {
+				if tag, ok := pitchPartOfSpeechTags[name]; ok {
+					partTags = append(partTags, tag)
+				} else {
+					partTags = append(partTags, name)
+				}
+			}
+			part = strings.TrimSpace(part[len(matches[0]):])
+		}
+
+		part = strings.Trim(part, "[]［］")
+		if position, ok := parsePitchPosition(part); ok {
+			positions = append(positions, position)
+			tags = append(tags, partTags)
+		}
+	}
+	return positions, tags
+}
+
+// Exports tab-separated accent lists, such as the accents.txt file of
+// Kanjium, with the expression, reading and accent positions on each
+// line. The reading may be empty for kana expressions, and may contain
+// nasal (か゚) and devoiced (く̥) markers.
+func pitchExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	reader, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	var (
+		metas    dbMetaList
+		tagNames []string
+	)
+
+	scanner := bufio.NewScanner(reader)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimPrefix(scanner.Text(), "\uFEFF")
+		if strings.HasPrefix(line, "#") || strings.TrimSpace(line) == "" {
+			continue
+		}
+
+		parts := strings.Split(line, "\t")
+		if len(parts) < 3 {
+			fmt.Printf("Skipping line %d with too few columns\n", lineNumber)
+			continue
+		}
+
+		expression := strings.TrimSpace(parts[0])
+		reading := strings.TrimSpace(parts[1])
+		if reading == "" {
+			reading = expression
+		}
+
+		positions, positionTags := parsePitchList(parts[2])
+		if len(positions) == 0 {
+			fmt.Printf("Skipping line %d without accent positions\n", lineNumber)
+			continue
+		}
+
+		// accents with different parts of speech need separate records,
+		// since makePitchMeta applies the same tags to every position
+		var pitches []pitchAccent
+		for i, position := range positions {
+			meta, ok := makePitchMeta(expression, reading, []int{position}, positionTags[i])
+			if !ok {
+				continue
+			}
+			pitches = append(pitches, meta.Data.(pitchAccentData).Pitches...)
+			tagNames = appendStringUnique(tagNames, positionTags[i]...)
+		}
+
+		if len(pitches) == 0 {
+			fmt.Printf("Skipping line %d with an invalid reading or accent\n", lineNumber)
+			continue
+		}
+
+		morae, _, _ := pitchMorae(reading)
+		metas = append(metas, dbMeta{expression, "pitch", pitchAccentData{
+			Reading: strings.Join(morae, ""),
+			Pitches: pitches,
+		}})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if title == "" {
+		title = "Pitch Accent"
+	}
+
+	var tags dbTagList
+	for _, name := range tagNames {
+		tags = append(tags, dbTag{Name: name, Category: "partOfSpeech", Order: -3})
+	}
+
+	recordData := map[string]dbRecordList{
+		"term_meta": metas.crush(),
+		"tag":       tags.crush(),
+	}
+
+	index := dbIndex{
+		Title:     title,
+		Revision:  "pitch1",
+		Sequenced: false,
+	}
+
+	return writeDb(
+		outputPath,
+		index,
+		recordData,
+		stride,
+		pretty,
+	)
+}
@@ -0,0 +1,106 @@
+package yomichan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestFindPitchPositions(t *testing.T) {
+	tests := []struct {
+		text string
+		want []int
+	}{
+		{"あい [1]【愛】", []int{1}},
+		{"はし【橋】[2]", []int{2}},
+		{"［０・２］", []int{0, 2}},
+		{"[0,2]", []int{0, 2}},
+		{"［１０］", []int{10}},
+		{"[1] [2]", []int{1}},
+		{"かみ【紙】", nil},
+		{"[名]", nil},
+	}
+
+	for _, test := range tests {
+		if got := findPitchPositions(test.text); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("findPitchPositions(%q) = %v, want %v", test.text, got, test.want)
+		}
+	}
+}
+
+func TestMakePitchMeta(t *testing.T) {
+	tests := []struct {
+		expression string
+		reading    string
+		positions  []int
+		tags       []string
+		want       dbMeta
+		ok         bool
+	}{
+		{
+			expression: "学校",
+			reading:    "がっこう",
+			positions:  []int{0},
+			want:       dbMeta{"学校", "pitch", pitchAccentData{Reading: "がっこう", Pitches: []pitchAccent{{Position: 0}}}},
+			ok:         true,
+		},
+		{
+			expression: "写真",
+			reading:    "しゃしん",
+			positions:  []int{0, 3, 4},
+			want:       dbMeta{"写真", "pitch", pitchAccentData{Reading: "しゃしん", Pitches: []pitchAccent{{Position: 0}, {Position: 3}}}},
+			ok:         true,
+		},
+		{
+			expression: "",
+			reading:    "か゚っこう",
+			positions:  []int{1},
+			want:       dbMeta{"かっこう", "pitch", pitchAccentData{Reading: "かっこう", Pitches: []pitchAccent{{Position: 1, Nasal: []int{1}}}}},
+			ok:         true,
+		},
+		{
+			expression: "草",
+			reading:    "く̥さ",
+			positions:  []int{2},
+			tags:       []string{"n"},
+			want:       dbMeta{"草", "pitch", pitchAccentData{Reading: "くさ", Pitches: []pitchAccent{{Position: 2, Devoice: []int{1}, Tags: []string{"n"}}}}},
+			ok:         true,
+		},
+		{expression: "木", reading: "き", positions: []int{2}},
+		{expression: "愛", reading: "愛", positions: []int{1}},
+		{expression: "愛", reading: "あい", positions: nil},
+		{expression: "愛", reading: "", positions: []int{1}},
+	}
+
+	for _, test := range tests {
+		got, ok := makePitchMeta(test.expression, test.reading, test.positions, test.tags)
+		if ok != test.ok || ok && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("makePitchMeta(%q, %q, %v) = %+v, %v, want %+v, %v", test.expression, test.reading, test.positions, got, ok, test.want, test.ok)
+		}
+	}
+}
+
+func TestParsePitchList(t *testing.T) {
+	tests := []struct {
+		text      string
+		positions []int
+		tags      [][]string
+	}{
+		{"0", []int{0}, [][]string{nil}},
+		{"0,2", []int{0, 2}, [][]string{nil, nil}},
+		{"０・２", []int{0, 2}, [][]string{nil, nil}},
+		{"[1]; [3]", []int{1, 3}, [][]string{nil, nil}},
+		{"(名)0,(副)1", []int{0, 1}, [][]string{{"n"}, {"adv"}}},
+		{"（形動）１", []int{1}, [][]string{{"adj-na"}}},
+		{"(名・副)0,2", []int{0, 2}, [][]string{{"n", "adv"}, nil}},
+		{"(名詞)3", []int{3}, [][]string{{"名詞"}}},
+		{"0,x,1", []int{0, 1}, [][]string{nil, nil}},
+		{"", nil, nil},
+	}
+
+	for _, test := range tests {
+		positions, tags := parsePitchList(test.text)
+		if !reflect.DeepEqual(positions, test.positions) || !reflect.DeepEqual(tags, test.tags) {
+			t.Errorf("parsePitchList(%q) = %v, %q, want %v, %q", test.text, positions, tags, test.positions, test.tags)
+		}
+	}
+}
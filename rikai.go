@@ -118,7 +118,7 @@ func rikaiExtractTerms(rows *sql.Rows) (dbTermList, error) {
 	return terms, nil
 }
 
-func rikaiExportDb(inputPath, outputPath, language, title string, stride int, pretty bool) error {
+func rikaiExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
 	db, err := sql.Open("sqlite3", inputPath)
 	if err != nil {
 		return err
@@ -139,17 +139,9 @@ func rikaiExportDb(inputPath, outputPath, language, title string, stride int, pr
 		title = "Rikai"
 	}
 
-	tags := dbTagList{
-		dbTag{Name: "P", Category: "popular", Order: -10},
-		dbTag{Name: "exp", Category: "expression", Order: -5},
-		dbTag{Name: "id", Category: "expression", Order: -5},
-		dbTag{Name: "arch", Category: "archaism", Order: -4},
-		dbTag{Name: "iK", Category: "archaism", Order: -4},
-	}
-
 	recordData := map[string]dbRecordList{
 		"term": terms.crush(),
-		"tag":  tags.crush(),
+		"tag":  rikaiTags().crush(),
 	}
 
 	index := dbIndex{
@@ -167,6 +159,16 @@ func rikaiExportDb(inputPath, outputPath, language, title string, stride int, pr
 	)
 }
 
+func rikaiTags() dbTagList {
+	return dbTagList{
+		dbTag{Name: "P", Category: "popular", Order: -10},
+		dbTag{Name: "exp", Category: "expression", Order: -5},
+		dbTag{Name: "id", Category: "expression", Order: -5},
+		dbTag{Name: "arch", Category: "archaism", Order: -4},
+		dbTag{Name: "iK", Category: "archaism", Order: -4},
+	}
+}
+
 func rikaiTagParsed(tag string) bool {
 	tags := []string{
 		"Buddh",
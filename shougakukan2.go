@@ -28,13 +28,17 @@ func makeShougakukan2Extractor() epwingExtractor {
 }
 
 func (e *shougakukan2Extractor) extractTerms(entry zig.BookEntry, sequence int) []dbTerm {
-	matches := e.partsExp.FindStringSubmatch(entry.Heading)
-	if matches == nil {
+	// pitch accent entries are exported as term meta instead
+	if e.pitchExp.MatchString(entry.Text) {
 		return nil
 	}
 
-	// skip pitch accent entries
-	if e.pitchExp.MatchString(entry.Text) {
+	return e.extractHeadwordTerms(entry, sequence)
+}
+
+func (e *shougakukan2Extractor) extractHeadwordTerms(entry zig.BookEntry, sequence int) []dbTerm {
+	matches := e.partsExp.FindStringSubmatch(entry.Heading)
+	if matches == nil {
 		return nil
 	}
 
@@ -90,12 +94,15 @@ func (e *shougakukan2Extractor) extractTerms(entry zig.BookEntry, sequence int)
 		}
 
 		for _, expression := range expressions {
-			terms = append(terms, dbTerm{
+			term := dbTerm{
 				Expression: expression,
 				Reading:    reading,
 				Glossary:   []any{entry.Text},
 				Sequence:   sequence,
-			})
+			}
+
+			e.exportRules(&term, nil)
+			terms = append(terms, term)
 		}
 	}
 
@@ -106,10 +113,28 @@ func (*shougakukan2Extractor) extractKanji(entry zig.BookEntry) []dbKanji {
 	return nil
 }
 
+func (e *shougakukan2Extractor) extractTermMeta(entry zig.BookEntry, terms []dbTerm) []dbMeta {
+	if !e.pitchExp.MatchString(entry.Text) {
+		return nil
+	}
+
+	// pitch accent entries have no terms of their own, so the headwords
+	// are extracted here instead
+	return epwingPitchMeta(e.extractHeadwordTerms(entry, 0), entry.Text)
+}
+
+func (e *shougakukan2Extractor) exportRules(term *dbTerm, tags []string) {
+	term.addRules(japaneseGrammarRules(term.Expression, tags)...)
+}
+
 func (*shougakukan2Extractor) getRevision() string {
 	return "shougakukan2"
 }
 
+func (*shougakukan2Extractor) getTags() dbTagList {
+	return nil
+}
+
 func (*shougakukan2Extractor) getFontNarrow() map[int]string {
 	return map[int]string{
 		0xA121: "\u00A9",
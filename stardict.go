@@ -0,0 +1,339 @@
+package yomichan
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type stardictIndexEntry struct {
+	word   string
+	offset uint64
+	size   uint32
+}
+
+type stardictSynonym struct {
+	word  string
+	index uint32
+}
+
+type stardictField struct {
+	kind byte
+	data []byte
+}
+
+// Opens the first of the given paths which exists, transparently
+// decompressing files ending in .gz or .dz (dictzip).
+func stardictOpen(paths ...string) (io.ReadCloser, error) {
+	for _, path := range paths {
+		fp, err := os.Open(path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+
+		if !strings.HasSuffix(path, ".gz") && !strings.HasSuffix(path, ".dz") {
+			return fp, nil
+		}
+
+		reader, err := gzip.NewReader(fp)
+		if err != nil {
+			fp.Close()
+			return nil, err
+		}
+
+		return struct {
+			io.Reader
+			io.Closer
+		}{reader, fp}, nil
+	}
+
+	return nil, os.ErrNotExist
+}
+
+func stardictReadFile(paths ...string) ([]byte, error) {
+	reader, err := stardictOpen(paths...)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+func stardictLoadInfo(path string) (map[string]string, error) {
+	fp, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	scanner := bufio.NewScanner(fp)
+	if !scanner.Scan() || strings.TrimPrefix(scanner.Text(), "\uFEFF") != "StarDict's dict ifo file" {
+		return nil, errors.New("invalid StarDict info file")
+	}
+
+	info := make(map[string]string)
+	for scanner.Scan() {
+		if key, value, ok := strings.Cut(scanner.Text(), "="); ok {
+			info[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	return info, scanner.Err()
+}
+
+func stardictLoadIndex(data []byte, offsetBits int) ([]stardictIndexEntry, error) {
+	var entries []stardictIndexEntry
+
+	offsetSize := offsetBits / 8
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, 0)
+		if end == -1 || len(data) < end+1+offsetSize+4 {
+			return nil, errors.New("truncated StarDict index file")
+		}
+
+		entry := stardictIndexEntry{word: string(data[:end])}
+		data = data[end+1:]
+
+		if offsetSize == 8 {
+			entry.offset = binary.BigEndian.Uint64(data)
+		} else {
+			entry.offset = uint64(binary.BigEndian.Uint32(data))
+		}
+		entry.size = binary.BigEndian.Uint32(data[offsetSize:])
+		data = data[offsetSize+4:]
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+func stardictLoadSynonyms(data []byte) ([]stardictSynonym, error) {
+	var synonyms []stardictSynonym
+
+	for len(data) > 0 {
+		end := bytes.IndexByte(data, 0)
+		if end == -1 || len(data) < end+5 {
+			return nil, errors.New("truncated StarDict synonym file")
+		}
+
+		synonyms = append(synonyms, stardictSynonym{
+			word:  string(data[:end]),
+			index: binary.BigEndian.Uint32(data[end+1:]),
+		})
+		data = data[end+5:]
+	}
+
+	return synonyms, nil
+}
+
+// Splits the data of a dictionary entry into its typed fields. Lower
+// case types are null-terminated strings and upper case types are
+// binary data prefixed by their size, except for the last field of an
+// entry described by sametypesequence, which spans the rest of the
+// entry.
+func stardictParseFields(data []byte, sameTypeSequence string) ([]stardictField, error) {
+	var fields []stardictField
+
+	readField := func(kind byte, last bool) error {
+		field := stardictField{kind: kind}
+		switch {
+		case last:
+			field.data = bytes.TrimRight(data, "\x00")
+			data = nil
+		case kind >= 'a' && kind <= 'z':
+			end := bytes.IndexByte(data, 0)
+			if end == -1 {
+				field.data = data
+				data = nil
+			} else {
+				field.data = data[:end]
+				data = data[end+1:]
+			}
+		default:
+			if len(data) < 4 {
+				return errors.New("truncated StarDict entry")
+			}
+			size := binary.BigEndian.Uint32(data)
+			if uint64(len(data)-4) < uint64(size) {
+				return errors.New("truncated StarDict entry")
+			}
+			field.data = data[4 : 4+size]
+			data = data[4+size:]
+		}
+
+		fields = append(fields, field)
+		return nil
+	}
+
+	if sameTypeSequence != "" {
+		for i := 0; i < len(sameTypeSequence); i++ {
+			if err := readField(sameTypeSequence[i], i == len(sameTypeSequence)-1); err != nil {
+				return nil, err
+			}
+		}
+		return fields, nil
+	}
+
+	for len(data) > 0 {
+		kind := data[0]
+		data = data[1:]
+		if err := readField(kind, false); err != nil {
+			return nil, err
+		}
+	}
+
+	return fields, nil
+}
+
+func stardictLinkQuery(href string) (string, bool) {
+	for _, prefix := range []string{"bword://", "entry://"} {
+		if strings.HasPrefix(href, prefix) {
+			return strings.TrimPrefix(href, prefix), true
+		}
+	}
+	return "", false
+}
+
+func stardictExtractTerm(entry stardictIndexEntry, fields []stardictField, sequence int) dbTerm {
+	converter := markupConverter{resolveLink: stardictLinkQuery}
+
+	term := dbTerm{
+		Expression: entry.word,
+		Sequence:   sequence,
+	}
+
+	for _, field := range fields {
+		text := strings.TrimSpace(string(field.data))
+		if text == "" {
+			continue
+		}
+
+		switch field.kind {
+		case 'm', 'l':
+			term.Glossary = append(term.Glossary, text)
+		case 't':
+			term.Glossary = append(term.Glossary, "["+text+"]")
+		case 'y':
+			term.Reading = text
+		case 'h', 'g':
+			if contents := converter.htmlContent(text); len(contents) > 0 {
+				term.Glossary = append(term.Glossary, contentStructure(contents...))
+			}
+		case 'x':
+			if contents := converter.xdxfContent(text); len(contents) > 0 {
+				term.Glossary = append(term.Glossary, contentStructure(contents...))
+			}
+		}
+	}
+
+	return term
+}
+
+func stardictExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	info, err := stardictLoadInfo(inputPath)
+	if err != nil {
+		return err
+	}
+
+	basePath := strings.TrimSuffix(inputPath, ".ifo")
+
+	offsetBits := 32
+	if bits, ok := info["idxoffsetbits"]; ok {
+		if offsetBits, err = strconv.Atoi(bits); err != nil || (offsetBits != 32 && offsetBits != 64) {
+			return fmt.Errorf("unsupported StarDict index offset size '%s'", bits)
+		}
+	}
+
+	indexData, err := stardictReadFile(basePath+".idx", basePath+".idx.gz")
+	if err != nil {
+		return err
+	}
+
+	entries, err := stardictLoadIndex(indexData, offsetBits)
+	if err != nil {
+		return err
+	}
+
+	dictData, err := stardictReadFile(basePath+".dict", basePath+".dict.dz")
+	if err != nil {
+		return err
+	}
+
+	var synonyms []stardictSynonym
+	if synData, err := stardictReadFile(basePath + ".syn"); err == nil {
+		if synonyms, err = stardictLoadSynonyms(synData); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var terms dbTermList
+	for i, entry := range entries {
+		if entry.offset+uint64(entry.size) > uint64(len(dictData)) {
+			return fmt.Errorf("entry '%s' is outside of the StarDict dictionary file", entry.word)
+		}
+
+		fields, err := stardictParseFields(dictData[entry.offset:entry.offset+uint64(entry.size)], info["sametypesequence"])
+		if err != nil {
+			return fmt.Errorf("failed to parse entry '%s': %w", entry.word, err)
+		}
+
+		terms = append(terms, stardictExtractTerm(entry, fields, i+1))
+	}
+
+	for _, synonym := range synonyms {
+		if int(synonym.index) >= len(terms) {
+			continue
+		}
+
+		term := terms[synonym.index]
+		if term.Expression == synonym.word {
+			continue
+		}
+
+		term.Expression = synonym.word
+		terms = append(terms, term)
+	}
+
+	if title == "" {
+		title = info["bookname"]
+	}
+
+	revision := "stardict"
+	if date := info["date"]; date != "" {
+		revision += "." + date
+	}
+
+	recordData := map[string]dbRecordList{
+		"term": terms.crush(),
+	}
+
+	index := dbIndex{
+		Title:       title,
+		Revision:    revision,
+		Sequenced:   true,
+		Author:      info["author"],
+		Url:         info["website"],
+		Description: info["description"],
+	}
+
+	return writeDb(
+		outputPath,
+		index,
+		recordData,
+		stride,
+		pretty,
+	)
+}
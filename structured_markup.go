@@ -0,0 +1,324 @@
+package yomichan
+
+import (
+	"html"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+// A loosely parsed HTML (or HTML-like) element. Children are either
+// strings or *markupNode values.
+type markupNode struct {
+	tag      string
+	attrs    map[string]string
+	children []any
+}
+
+type markupConverter struct {
+	// resolves the href attribute of a link into a Yomichan search query
+	resolveLink func(href string) (string, bool)
+	// resolves the src attribute of an image into structured content
+	resolveImage func(src string) (any, bool)
+}
+
+var (
+	markupTagExp      = regexp.MustCompile(`(?s)<!--.*?-->|<(/?)([a-zA-Z][a-zA-Z0-9:_-]*)([^>]*?)(/?)>`)
+	markupAttrExp     = regexp.MustCompile(`([a-zA-Z_:][-a-zA-Z0-9_:.]*)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+	markupSpaceExp    = regexp.MustCompile(`\s+`)
+	markupVoidTags    = []string{"br", "hr", "img", "meta", "link", "input", "wbr", "source", "col", "area"}
+	markupIgnoredTags = []string{"script", "style", "head", "title"}
+
+	// Elements which implicitly close an open element of the listed types.
+	markupImplicitClose = map[string][]string{
+		"li": {"li"},
+		"p":  {"p"},
+		"dt": {"dt", "dd"},
+		"dd": {"dt", "dd"},
+		"tr": {"tr", "td", "th"},
+		"td": {"td", "th"},
+		"th": {"td", "th"},
+	}
+)
+
+// Parses HTML into a tree of markupNode values. Parsing is lenient:
+// unknown closing tags are ignored and unclosed tags are closed at the
+// end of their parent or at the end of the document.
+func parseMarkup(text string) *markupNode {
+	root := &markupNode{}
+	stack := []*markupNode{root}
+
+	appendText := func(text string) {
+		if len(text) == 0 {
+			return
+		}
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, html.UnescapeString(text))
+	}
+
+	position := 0
+	for _, match := range markupTagExp.FindAllStringSubmatchIndex(text, -1) {
+		appendText(text[position:match[0]])
+		position = match[1]
+
+		if match[4] == -1 {
+			// comment
+			continue
+		}
+
+		isClosing := match[3] > match[2]
+		tag := strings.ToLower(text[match[4]:match[5]])
+
+		if isClosing {
+			for i := len(stack) - 1; i > 0; i-- {
+				if stack[i].tag == tag {
+					stack = stack[:i]
+					break
+				}
+			}
+			continue
+		}
+
+		node := &markupNode{tag: tag, attrs: make(map[string]string)}
+		for _, attr := range markupAttrExp.FindAllStringSubmatch(text[match[6]:match[7]], -1) {
+			node.attrs[strings.ToLower(attr[1])] = html.UnescapeString(attr[2] + attr[3] + attr[4])
+		}
+
+		for len(stack) > 1 && slices.Contains(markupImplicitClose[tag], stack[len(stack)-1].tag) {
+			stack = stack[:len(stack)-1]
+		}
+
+		parent := stack[len(stack)-1]
+		parent.children = append(parent.children, node)
+
+		isSelfClosing := match[9] > match[8]
+		if !isSelfClosing && !slices.Contains(markupVoidTags, tag) {
+			stack = append(stack, node)
+		}
+	}
+	appendText(text[position:])
+
+	return root
+}
+
+// Returns the concatenated text of a node and its descendants.
+func (node *markupNode) text() string {
+	var builder strings.Builder
+	for _, child := range node.children {
+		switch v := child.(type) {
+		case string:
+			builder.WriteString(v)
+		case *markupNode:
+			builder.WriteString(v.text())
+		}
+	}
+	return builder.String()
+}
+
+// Converts HTML into structured content. The result can be passed
+// directly to contentStructure.
+func (c markupConverter) htmlContent(text string) []any {
+	return c.convertChildren(parseMarkup(text))
+}
+
+// Converts XDXF article markup into structured content. XDXF elements
+// are first mapped onto their closest HTML equivalents.
+func (c markupConverter) xdxfContent(text string) []any {
+	root := parseMarkup(text)
+	xdxfToHtml(root)
+	return c.convertChildren(root)
+}
+
+func xdxfToHtml(node *markupNode) {
+	for _, child := range node.children {
+		child, ok := child.(*markupNode)
+		if !ok {
+			continue
+		}
+
+		switch child.tag {
+		case "k", "dtrn":
+			child.tag = "b"
+		case "abr", "abbr", "ex", "co":
+			child.tag = "i"
+		case "kref", "iref":
+			child.tag = "a"
+			if _, ok := child.attrs["href"]; !ok {
+				child.attrs["href"] = "bword://" + child.text()
+			}
+		case "tr":
+			// transcription, not a table row
+			child.tag = "span"
+			child.children = append(append([]any{"["}, child.children...), "]")
+		case "def":
+			child.tag = "div"
+		case "gr", "c", "opt", "nu", "sr", "mrkd", "categ", "deftext":
+			child.tag = "span"
+		}
+
+		xdxfToHtml(child)
+	}
+}
+
+func (c markupConverter) convertChildren(node *markupNode) []any {
+	contents := []any{}
+	for _, child := range node.children {
+		switch v := child.(type) {
+		case string:
+			if text := markupSpaceExp.ReplaceAllLiteralString(v, " "); text != "" {
+				contents = append(contents, text)
+			}
+		case *markupNode:
+			if content := c.convertNode(v); content != nil {
+				contents = append(contents, content)
+			}
+		}
+	}
+	return contents
+}
+
+func (c markupConverter) convertNode(node *markupNode) any {
+	if slices.Contains(markupIgnoredTags, node.tag) {
+		return nil
+	}
+
+	attr := contentAttr{lang: node.attrs["lang"]}
+
+	switch node.tag {
+	case "br":
+		return map[string]string{"tag": "br"}
+	case "img":
+		if c.resolveImage != nil {
+			if image, ok := c.resolveImage(node.attrs["src"]); ok {
+				return image
+			}
+		}
+		if alt := node.attrs["alt"]; alt != "" {
+			return alt
+		}
+		return nil
+	case "ruby":
+		return c.convertRuby(attr, node)
+	case "a":
+		return c.convertLink(attr, node)
+	}
+
+	children := c.convertChildren(node)
+	if len(children) == 0 {
+		return nil
+	}
+
+	switch node.tag {
+	case "b", "strong":
+		attr.fontWeight = "bold"
+	case "i", "em", "cite", "var", "dfn":
+		attr.fontStyle = "italic"
+	case "u", "ins":
+		attr.textDecorationLine = []string{"underline"}
+	case "s", "del", "strike":
+		attr.textDecorationLine = []string{"line-through"}
+	case "sup":
+		attr.verticalAlign = "super"
+		attr.fontSize = "smaller"
+	case "sub":
+		attr.verticalAlign = "sub"
+		attr.fontSize = "smaller"
+	case "small":
+		attr.fontSize = "smaller"
+	case "big":
+		attr.fontSize = "larger"
+	case "h1", "h2", "h3", "h4", "h5", "h6":
+		attr.fontWeight = "bold"
+		return contentDiv(attr, children...)
+	case "blockquote", "dd":
+		attr.marginLeft = 1
+		return contentDiv(attr, children...)
+	case "dt":
+		attr.fontWeight = "bold"
+		return contentDiv(attr, children...)
+	case "p", "div", "center", "dl", "section", "article", "header", "footer", "body", "html":
+		return contentDiv(attr, children...)
+	case "ul":
+		return contentUnorderedList(attr, children...)
+	case "ol":
+		return contentOrderedList(attr, children...)
+	case "li":
+		return contentListItem(attr, children...)
+	case "table":
+		return contentTable(attr, children...)
+	case "thead":
+		return contentTableHead(attr, children...)
+	case "tbody":
+		return contentTableBody(attr, children...)
+	case "tr":
+		return contentTableRow(attr, children...)
+	case "th":
+		return contentTableHeadCell(attr, children...)
+	case "td":
+		return contentTableCell(attr, children...)
+	}
+
+	return contentSpan(attr, children...)
+}
+
+func (c markupConverter) convertRuby(attr contentAttr, node *markupNode) any {
+	var base []any
+	var ruby string
+	for _, child := range node.children {
+		switch v := child.(type) {
+		case string:
+			base = append(base, v)
+		case *markupNode:
+			switch v.tag {
+			case "rt":
+				ruby += v.text()
+			case "rp":
+				continue
+			default:
+				if content := c.convertNode(v); content != nil {
+					base = append(base, content)
+				}
+			}
+		}
+	}
+	if len(base) == 0 {
+		return nil
+	}
+	if ruby == "" {
+		return contentSpan(attr, base...)
+	}
+	return contentRuby(attr, ruby, base...)
+}
+
+func (c markupConverter) convertLink(attr contentAttr, node *markupNode) any {
+	children := c.convertChildren(node)
+	href := node.attrs["href"]
+
+	if c.resolveLink != nil {
+		if query, ok := c.resolveLink(href); ok {
+			if len(children) == 0 {
+				return contentInternalLink(attr, query)
+			}
+			return contentInternalLink(attr, query, children...)
+		}
+	}
+
+	if len(children) == 0 {
+		return nil
+	}
+
+	if strings.HasPrefix(href, "http://") || strings.HasPrefix(href, "https://") {
+		link := map[string]any{
+			"tag":     "a",
+			"href":    href,
+			"content": contentReduce(children),
+		}
+		if attr.lang != "" {
+			link["lang"] = attr.lang
+		}
+		return link
+	}
+
+	return contentSpan(attr, children...)
+}
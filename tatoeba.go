@@ -0,0 +1,365 @@
+package yomichan
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"golang.org/x/exp/slices"
+)
+
+const (
+	tatoebaAttribution = "This dictionary includes example sentences from the Tatoeba Project (https://tatoeba.org/), released under the Creative Commons Attribution 2.0 France license, and the Tanaka Corpus index maintained by the Electronic Dictionaries Research Group."
+
+	// Limits the number of examples shown for each headword.
+	tatoebaMaxExamples = 5
+)
+
+// Matches a word of a B-line, such as "食べる(たべる)[01]{食べた}~",
+// which gives the headword, its reading, the JMdict sense, the form used
+// in the sentence and whether the sentence is a checked good example.
+var tatoebaWordExp = regexp.MustCompile(`^([^(\[{~]+)(?:\(([^)]+)\))?(?:\[\d+\])?(?:\{([^}]+)\})?(~)?$`)
+
+// Tatoeba uses ISO 639-3 codes, which differ from the bibliographic
+// codes used elsewhere for a few languages.
+var tatoebaLanguageCodes = map[string]string{
+	"dut": "nld",
+	"fre": "fra",
+	"ger": "deu",
+}
+
+type tatoebaWord struct {
+	headword string
+	reading  string
+	surface  string
+	good     bool
+}
+
+type tatoebaSentence struct {
+	text        string
+	translation string
+	words       []tatoebaWord
+}
+
+type tatoebaExample struct {
+	sentence *tatoebaSentence
+	word     int
+}
+
+func tatoebaParseIndex(line string) []tatoebaWord {
+	var words []tatoebaWord
+	for _, field := range strings.Fields(line) {
+		matches := tatoebaWordExp.FindStringSubmatch(field)
+		if matches == nil {
+			continue
+		}
+
+		word := tatoebaWord{
+			headword: matches[1],
+			reading:  matches[2],
+			surface:  matches[3],
+			good:     matches[4] != "",
+		}
+		if word.surface == "" {
+			word.surface = word.headword
+		}
+
+		words = append(words, word)
+	}
+	return words
+}
+
+// Loads the readings of each JMdict headword.
+func tatoebaLoadReadings(path string) (map[string][]string, error) {
+	dictionary, _, _, err := loadJmdictFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	readings := make(map[string][]string)
+	for _, entry := range dictionary.Entries {
+		for _, headword := range extractHeadwords(entry) {
+			if !headword.IsSearchOnly {
+				readings[headword.Expression] = appendStringUnique(readings[headword.Expression], headword.Reading)
+			}
+		}
+	}
+
+	return readings, nil
+}
+
+// Calls visit with the tab-separated fields of each line of a Tatoeba
+// export file.
+func tatoebaReadFile(path string, visit func(fields []string)) error {
+	fp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	scanner := bufio.NewScanner(fp)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		visit(strings.Split(strings.TrimSuffix(scanner.Text(), "\r"), "\t"))
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Splits a headword into the kana shared with its reading at either end
+// and the part in between, which is written with kanji.
+func tatoebaKanjiStem(headword, reading string) (string, string, string, bool) {
+	expression, kana := []rune(headword), []rune(reading)
+
+	prefix := 0
+	for prefix < len(expression) && prefix < len(kana) && expression[prefix] == kana[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(expression)-prefix && suffix < len(kana)-prefix && expression[len(expression)-1-suffix] == kana[len(kana)-1-suffix] {
+		suffix++
+	}
+
+	stem := expression[prefix : len(expression)-suffix]
+	stemReading := kana[prefix : len(kana)-suffix]
+	if len(stem) == 0 || len(stemReading) == 0 {
+		return "", "", "", false
+	}
+
+	return string(expression[:prefix]), string(stem), string(stemReading), true
+}
+
+// Adds the reading of a word to the form used in the sentence. Inflected
+// forms only get ruby when they begin with the kanji of the headword.
+func tatoebaWordContent(word tatoebaWord) []any {
+	if word.reading == "" || isKanaOnly(word.headword) {
+		return []any{word.surface}
+	}
+
+	prefix, stem, stemReading, ok := tatoebaKanjiStem(word.headword, word.reading)
+	if !ok || !strings.HasPrefix(word.surface, prefix+stem) {
+		return []any{word.surface}
+	}
+
+	contents := []any{}
+	if prefix != "" {
+		contents = append(contents, prefix)
+	}
+	contents = append(contents, contentRuby(contentAttr{}, stemReading, stem))
+	if rest := strings.TrimPrefix(word.surface, prefix+stem); rest != "" {
+		contents = append(contents, rest)
+	}
+	return contents
+}
+
+// Builds a sentence with ruby over the indexed words, highlighting the
+// word the example is shown for.
+func tatoebaSentenceContent(sentence *tatoebaSentence, target int) []any {
+	var contents []any
+	text := sentence.text
+	for i, word := range sentence.words {
+		index := strings.Index(text, word.surface)
+		if index == -1 {
+			continue
+		}
+		if index > 0 {
+			contents = append(contents, text[:index])
+		}
+
+		wordContents := tatoebaWordContent(word)
+		if i == target {
+			contents = append(contents, contentSpan(contentAttr{fontWeight: "bold", data: map[string]string{"content": "highlight"}}, wordContents...))
+		} else {
+			contents = append(contents, wordContents...)
+		}
+
+		text = text[index+len(word.surface):]
+	}
+	if text != "" {
+		contents = append(contents, text)
+	}
+	return contents
+}
+
+func tatoebaExampleContent(example tatoebaExample, lang string) any {
+	return contentDiv(
+		contentAttr{marginLeft: 1, data: map[string]string{"content": "example"}},
+		contentDiv(contentAttr{lang: "ja"}, tatoebaSentenceContent(example.sentence, example.word)...),
+		contentDiv(contentAttr{lang: lang, fontSize: "smaller"}, example.sentence.translation),
+	)
+}
+
+func tatoebaExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	code, ok := langNameToCode[language]
+	if !ok {
+		return errors.New("Unrecognized language parameter: " + language)
+	}
+
+	translationLanguage := code
+	if tatoebaCode, ok := tatoebaLanguageCodes[code]; ok {
+		translationLanguage = tatoebaCode
+	}
+
+	directory := inputPath
+	if info, err := os.Stat(inputPath); err != nil {
+		return err
+	} else if !info.IsDir() {
+		directory = filepath.Dir(inputPath)
+	}
+
+	// JMdict fills in the readings of unambiguous words which the index
+	// gives no reading for, and limits the headwords to those in JMdict
+	var (
+		lexicon map[string][]string
+		err     error
+	)
+
+	if options.Lexicon != "" {
+		if lexicon, err = tatoebaLoadReadings(options.Lexicon); err != nil {
+			return err
+		}
+	}
+
+	// the B-line index lists the Japanese sentences which have examples
+	var (
+		sentences = make(map[string]*tatoebaSentence)
+		meanings  = make(map[string]string)
+		order     []string
+	)
+
+	err = tatoebaReadFile(filepath.Join(directory, "jpn_indices.csv"), func(fields []string) {
+		if len(fields) < 3 {
+			return
+		}
+		if words := tatoebaParseIndex(fields[2]); len(words) > 0 {
+			for i, word := range words {
+				if readings := lexicon[word.headword]; word.reading == "" && len(readings) == 1 && readings[0] != word.headword {
+					words[i].reading = readings[0]
+				}
+			}
+			sentences[fields[0]] = &tatoebaSentence{words: words}
+			meanings[fields[0]] = fields[1]
+			order = append(order, fields[0])
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	translations := make(map[string]string)
+	err = tatoebaReadFile(filepath.Join(directory, "sentences.csv"), func(fields []string) {
+		if len(fields) < 3 {
+			return
+		}
+		if sentence, ok := sentences[fields[0]]; ok && fields[1] == "jpn" {
+			sentence.text = fields[2]
+		} else if fields[1] == translationLanguage {
+			translations[fields[0]] = fields[2]
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	// prefer the translation the index was made from, then any other
+	// linked translation
+	for id, sentence := range sentences {
+		sentence.translation = translations[meanings[id]]
+	}
+
+	err = tatoebaReadFile(filepath.Join(directory, "links.csv"), func(fields []string) {
+		if len(fields) < 2 {
+			return
+		}
+		if sentence, ok := sentences[fields[0]]; ok && sentence.translation == "" {
+			sentence.translation = translations[fields[1]]
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	var (
+		headwords [][2]string
+		examples  = make(map[[2]string][]tatoebaExample)
+	)
+
+	// checked good examples are listed before the others
+	for _, good := range []bool{true, false} {
+		for _, id := range order {
+			sentence := sentences[id]
+			if sentence.text == "" || sentence.translation == "" {
+				continue
+			}
+			for i, word := range sentence.words {
+				if word.good != good {
+					continue
+				}
+				if _, ok := lexicon[word.headword]; lexicon != nil && !ok {
+					continue
+				}
+				headword := [2]string{word.headword, word.reading}
+				if _, ok := examples[headword]; !ok {
+					headwords = append(headwords, headword)
+				}
+				// sentences using a headword twice are only listed once
+				listed := slices.ContainsFunc(examples[headword], func(example tatoebaExample) bool {
+					return example.sentence == sentence
+				})
+				if !listed && len(examples[headword]) < tatoebaMaxExamples {
+					examples[headword] = append(examples[headword], tatoebaExample{sentence, i})
+				}
+			}
+		}
+	}
+
+	lang := ISOtoHTML[code]
+
+	var terms dbTermList
+	for _, headword := range headwords {
+		var contents []any
+		for _, example := range examples[headword] {
+			contents = append(contents, tatoebaExampleContent(example, lang))
+		}
+
+		terms = append(terms, dbTerm{
+			Expression: headword[0],
+			Reading:    headword[1],
+			Glossary:   []any{contentStructure(contents...)},
+		})
+	}
+
+	if title == "" {
+		title = "Tatoeba Examples"
+	}
+
+	recordData := map[string]dbRecordList{
+		"term": terms.crush(),
+	}
+
+	index := dbIndex{
+		Title:          title,
+		Revision:       "tatoeba",
+		Sequenced:      false,
+		Attribution:    tatoebaAttribution,
+		SourceLanguage: "ja",
+		TargetLanguage: lang,
+	}
+
+	return writeDb(
+		outputPath,
+		index,
+		recordData,
+		stride,
+		pretty,
+	)
+}
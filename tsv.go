@@ -0,0 +1,301 @@
+package yomichan
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The default mapping reads the expression, reading and glossary from
+// the first three columns.
+var tsvDefaultColumns = map[string][]string{
+	"expression": {"1"},
+	"reading":    {"2"},
+	"glossary":   {"3"},
+}
+
+var tsvRoles = []string{
+	"expression",
+	"reading",
+	"glossary",
+	"definitionTags",
+	"termTags",
+	"rules",
+	"score",
+	"sequence",
+}
+
+// Returns the delimiter given in the options, defaulting to a comma
+// for .csv files and a tab otherwise.
+func tsvDelimiter(inputPath, delimiter string) (rune, error) {
+	switch strings.ToLower(delimiter) {
+	case "":
+		if strings.ToLower(filepath.Ext(inputPath)) == ".csv" {
+			return ',', nil
+		}
+		return '\t', nil
+	case "tab", `\t`:
+		return '\t', nil
+	case "comma":
+		return ',', nil
+	case "semicolon":
+		return ';', nil
+	case "pipe":
+		return '|', nil
+	}
+
+	if utf8.RuneCountInString(delimiter) != 1 {
+		return 0, fmt.Errorf("invalid delimiter: %s", delimiter)
+	}
+
+	r, _ := utf8.DecodeRuneInString(delimiter)
+	return r, nil
+}
+
+// Resolves column names or 1-based indices into column positions.
+// Names require a header row.
+func tsvColumnIndices(names []string, header []string) ([]int, error) {
+	var indices []int
+	for _, name := range names {
+		if index, err := strconv.Atoi(name); err == nil {
+			if index < 1 {
+				return nil, fmt.Errorf("invalid column index: %d", index)
+			}
+			indices = append(indices, index-1)
+			continue
+		}
+
+		if header == nil {
+			return nil, fmt.Errorf("column %q can only be referenced by name with a header row", name)
+		}
+
+		found := false
+		for i, column := range header {
+			if strings.EqualFold(strings.TrimSpace(column), name) {
+				indices = append(indices, i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("column %q not found in header", name)
+		}
+	}
+	return indices, nil
+}
+
+func tsvColumnValues(record []string, indices []int) []string {
+	var values []string
+	for _, index := range indices {
+		if index < len(record) {
+			if value := strings.TrimSpace(record[index]); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// Skips the lines starting with "#" at the top of a file, returning how
+// many were skipped. Later lines are always read as rows, so that
+// expressions such as "#タグ" are kept.
+func tsvSkipComments(reader *bufio.Reader) (int, error) {
+	var count int
+	for {
+		prefix, _ := reader.Peek(len("\uFEFF#"))
+		if !bytes.HasPrefix(bytes.TrimPrefix(prefix, []byte("\uFEFF")), []byte("#")) {
+			return count, nil
+		}
+
+		count++
+		if _, err := reader.ReadString('\n'); err != nil {
+			if errors.Is(err, io.EOF) {
+				return count, nil
+			}
+			return count, err
+		}
+	}
+}
+
+// Moves the line numbers of a parse error past the skipped comments.
+func tsvOffsetError(err error, skipped int) error {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		parseErr.StartLine += skipped
+		parseErr.Line += skipped
+	}
+	return err
+}
+
+func tsvExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	fp, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	delimiter, err := tsvDelimiter(inputPath, options.Delimiter)
+	if err != nil {
+		return err
+	}
+
+	columnNames := make(map[string][]string)
+	for role, names := range tsvDefaultColumns {
+		columnNames[role] = names
+	}
+	for role, names := range options.Fields {
+		found := false
+		for _, name := range tsvRoles {
+			if strings.EqualFold(role, name) {
+				columnNames[name] = names
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("unsupported column mapping: %s", role)
+		}
+	}
+
+	buffered := bufio.NewReader(fp)
+	skipped, err := tsvSkipComments(buffered)
+	if err != nil {
+		return err
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+
+	var header []string
+	if options.Header {
+		if header, err = reader.Read(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return fmt.Errorf("%s: missing header row", inputPath)
+			}
+			return fmt.Errorf("%s: %w", inputPath, tsvOffsetError(err, skipped))
+		}
+		if len(header) > 0 {
+			header[0] = strings.TrimPrefix(header[0], "\uFEFF")
+		}
+	}
+
+	columns := make(map[string][]int)
+	for role, names := range columnNames {
+		if columns[role], err = tsvColumnIndices(names, header); err != nil {
+			return err
+		}
+	}
+
+	var (
+		terms    dbTermList
+		tagNames []string
+	)
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// parse errors include the line number
+			return fmt.Errorf("%s: %w", inputPath, tsvOffsetError(err, skipped))
+		}
+
+		line, _ := reader.FieldPos(0)
+		line += skipped
+
+		expressions := tsvColumnValues(record, columns["expression"])
+		if len(expressions) == 0 {
+			return fmt.Errorf("%s:%d: missing expression", inputPath, line)
+		}
+
+		term := dbTerm{Expression: expressions[0]}
+
+		if readings := tsvColumnValues(record, columns["reading"]); len(readings) > 0 {
+			term.Reading = readings[0]
+		}
+
+		for _, value := range tsvColumnValues(record, columns["glossary"]) {
+			glosses := []string{value}
+			if options.Separator != "" {
+				glosses = strings.Split(value, options.Separator)
+			}
+			for _, gloss := range glosses {
+				if gloss = strings.TrimSpace(gloss); gloss != "" {
+					term.Glossary = append(term.Glossary, gloss)
+				}
+			}
+		}
+
+		if len(term.Glossary) == 0 {
+			return fmt.Errorf("%s:%d: missing glossary", inputPath, line)
+		}
+
+		for _, value := range tsvColumnValues(record, columns["definitionTags"]) {
+			tags := strings.Fields(value)
+			term.addDefinitionTags(tags...)
+			tagNames = appendStringUnique(tagNames, tags...)
+		}
+
+		for _, value := range tsvColumnValues(record, columns["termTags"]) {
+			tags := strings.Fields(value)
+			term.addTermTags(tags...)
+			tagNames = appendStringUnique(tagNames, tags...)
+		}
+
+		for _, value := range tsvColumnValues(record, columns["rules"]) {
+			term.addRules(strings.Fields(value)...)
+		}
+
+		if values := tsvColumnValues(record, columns["score"]); len(values) > 0 {
+			if term.Score, err = strconv.Atoi(values[0]); err != nil {
+				return fmt.Errorf("%s:%d: invalid score: %s", inputPath, line, values[0])
+			}
+		}
+
+		if values := tsvColumnValues(record, columns["sequence"]); len(values) > 0 {
+			if term.Sequence, err = strconv.Atoi(values[0]); err != nil {
+				return fmt.Errorf("%s:%d: invalid sequence: %s", inputPath, line, values[0])
+			}
+		}
+
+		terms = append(terms, term)
+	}
+
+	if title == "" {
+		title = strings.TrimSuffix(filepath.Base(inputPath), filepath.Ext(inputPath))
+	}
+
+	var tags dbTagList
+	for _, name := range tagNames {
+		tags = append(tags, dbTag{Name: name})
+	}
+
+	recordData := map[string]dbRecordList{
+		"term": terms.crush(),
+		"tag":  tags.crush(),
+	}
+
+	index := dbIndex{
+		Title:     title,
+		Revision:  "tsv",
+		Sequenced: len(columns["sequence"]) > 0,
+	}
+
+	return writeDb(
+		outputPath,
+		index,
+		recordData,
+		stride,
+		pretty,
+	)
+}
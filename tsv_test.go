@@ -0,0 +1,86 @@
+package yomichan
+
+import (
+	"bufio"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTsvColumnIndices(t *testing.T) {
+	header := []string{"Word", " Kana ", "English", "Notes"}
+
+	tests := []struct {
+		names   []string
+		header  []string
+		want    []int
+		wantErr bool
+	}{
+		{[]string{"1", "2", "3"}, nil, []int{0, 1, 2}, false},
+		{[]string{"3", "3"}, nil, []int{2, 2}, false},
+		{[]string{"10"}, nil, []int{9}, false},
+		{[]string{"word", "kana"}, header, []int{0, 1}, false},
+		{[]string{"English", "4"}, header, []int{2, 3}, false},
+		{nil, header, nil, false},
+		{[]string{"0"}, nil, nil, true},
+		{[]string{"-1"}, header, nil, true},
+		{[]string{"Word"}, nil, nil, true},
+		{[]string{"Meaning"}, header, nil, true},
+	}
+
+	for _, test := range tests {
+		got, err := tsvColumnIndices(test.names, test.header)
+		if (err != nil) != test.wantErr || !reflect.DeepEqual(got, test.want) {
+			t.Errorf("tsvColumnIndices(%q, %q) = %v, %v, want %v (error %v)", test.names, test.header, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestTsvDelimiter(t *testing.T) {
+	tests := []struct {
+		path      string
+		delimiter string
+		want      rune
+		wantErr   bool
+	}{
+		{"words.tsv", "", '\t', false},
+		{"words.CSV", "", ',', false},
+		{"words.txt", "", '\t', false},
+		{"words.csv", "tab", '\t', false},
+		{"words.tsv", "Semicolon", ';', false},
+		{"words.tsv", "pipe", '|', false},
+		{"words.tsv", "・", '・', false},
+		{"words.tsv", "::", 0, true},
+	}
+
+	for _, test := range tests {
+		got, err := tsvDelimiter(test.path, test.delimiter)
+		if (err != nil) != test.wantErr || got != test.want {
+			t.Errorf("tsvDelimiter(%q, %q) = %q, %v, want %q (error %v)", test.path, test.delimiter, got, err, test.want, test.wantErr)
+		}
+	}
+}
+
+func TestTsvSkipComments(t *testing.T) {
+	tests := []struct {
+		text    string
+		skipped int
+		rest    string
+	}{
+		{"#comment\n# another\n猫\tねこ\tcat\n", 2, "猫\tねこ\tcat\n"},
+		{"\uFEFF# comment\nWord\tKana\n", 1, "Word\tKana\n"},
+		{"猫\tねこ\tcat\n#タグ\tたぐ\ttag\n", 0, "猫\tねこ\tcat\n#タグ\tたぐ\ttag\n"},
+		{"#only", 1, ""},
+		{"", 0, ""},
+	}
+
+	for _, test := range tests {
+		reader := bufio.NewReader(strings.NewReader(test.text))
+		skipped, err := tsvSkipComments(reader)
+		rest, _ := io.ReadAll(reader)
+		if err != nil || skipped != test.skipped || string(rest) != test.rest {
+			t.Errorf("tsvSkipComments(%q) = %d, %v leaving %q, want %d leaving %q", test.text, skipped, err, rest, test.skipped, test.rest)
+		}
+	}
+}
@@ -78,6 +78,7 @@ func (e *wadaiExtractor) extractTerms(entry zig.BookEntry, sequence int) []dbTer
 			Sequence:   sequence,
 		}
 
+		e.exportRules(&term, nil)
 		terms = append(terms, term)
 	}
 
@@ -88,10 +89,22 @@ func (e *wadaiExtractor) extractKanji(entry zig.BookEntry) []dbKanji {
 	return nil
 }
 
+func (e *wadaiExtractor) extractTermMeta(entry zig.BookEntry, terms []dbTerm) []dbMeta {
+	return nil
+}
+
+func (e *wadaiExtractor) exportRules(term *dbTerm, tags []string) {
+	term.addRules(japaneseGrammarRules(term.Expression, tags)...)
+}
+
 func (*wadaiExtractor) getRevision() string {
 	return "wadai1"
 }
 
+func (*wadaiExtractor) getTags() dbTagList {
+	return nil
+}
+
 func (*wadaiExtractor) getFontNarrow() map[int]string {
 	return map[int]string{
 		41267: "﹢",
@@ -0,0 +1,578 @@
+package yomichan
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+
+	"golang.org/x/exp/slices"
+)
+
+type wiktionaryForm struct {
+	Form string   `json:"form"`
+	Tags []string `json:"tags"`
+}
+
+type wiktionaryExample struct {
+	Text        string     `json:"text"`
+	English     string     `json:"english"`
+	Translation string     `json:"translation"`
+	Roman       string     `json:"roman"`
+	Ruby        [][]string `json:"ruby"`
+}
+
+type wiktionarySense struct {
+	Glosses  []string            `json:"glosses"`
+	Tags     []string            `json:"tags"`
+	Examples []wiktionaryExample `json:"examples"`
+}
+
+type wiktionarySound struct {
+	Ipa   string   `json:"ipa"`
+	Other string   `json:"other"`
+	Roman string   `json:"roman"`
+	Tags  []string `json:"tags"`
+}
+
+type wiktionaryHeadTemplate struct {
+	Name string            `json:"name"`
+	Args map[string]string `json:"args"`
+}
+
+type wiktionaryEntry struct {
+	Word          string                   `json:"word"`
+	Pos           string                   `json:"pos"`
+	LangCode      string                   `json:"lang_code"`
+	Forms         []wiktionaryForm         `json:"forms"`
+	Senses        []wiktionarySense        `json:"senses"`
+	Sounds        []wiktionarySound        `json:"sounds"`
+	HeadTemplates []wiktionaryHeadTemplate `json:"head_templates"`
+	Tags          []string                 `json:"tags"`
+}
+
+type phoneticTranscription struct {
+	Ipa  string   `json:"ipa"`
+	Tags []string `json:"tags,omitempty"`
+}
+
+type phoneticData struct {
+	Reading        string                  `json:"reading"`
+	Transcriptions []phoneticTranscription `json:"transcriptions"`
+}
+
+var (
+	// Parts of speech which map directly onto a JMdict part of speech.
+	wiktionaryPosCodes = map[string]string{
+		"noun":       "n",
+		"name":       "n",
+		"pron":       "pn",
+		"adv":        "adv",
+		"adnominal":  "adj-pn",
+		"particle":   "prt",
+		"conj":       "conj",
+		"intj":       "int",
+		"suffix":     "suf",
+		"prefix":     "pref",
+		"num":        "num",
+		"counter":    "ctr",
+		"phrase":     "exp",
+		"proverb":    "exp",
+		"aux":        "aux",
+		"classifier": "ctr",
+	}
+
+	// Sense tags which correspond to JMdict miscellaneous sense tags.
+	wiktionaryMiscCodes = map[string]string{
+		"abbreviation":   "abbr",
+		"archaic":        "arch",
+		"colloquial":     "col",
+		"dated":          "dated",
+		"derogatory":     "derog",
+		"euphemistic":    "euph",
+		"historical":     "hist",
+		"honorific":      "hon",
+		"humble":         "hum",
+		"humorous":       "joc",
+		"idiomatic":      "id",
+		"Internet":       "net-sl",
+		"literary":       "form",
+		"obsolete":       "obs",
+		"onomatopoeia":   "on-mim",
+		"poetic":         "poet",
+		"polite":         "pol",
+		"rare":           "rare",
+		"slang":          "sl",
+		"usually-kana":   "uk",
+		"vulgar":         "vulg",
+		"transitive":     "vt",
+		"intransitive":   "vi",
+		"sonkeigo":       "hon",
+		"kenjōgo":        "hum",
+		"teineigo":       "pol",
+		"childish":       "chn",
+		"familiar":       "fam",
+		"proverb":        "proverb",
+		"Kansai":         "ksb",
+		"Kansai-dialect": "ksb",
+	}
+
+	// Tags which describe the conjugation of verbs and adjectives.
+	wiktionaryConjugationCodes = map[string]string{
+		"godan":   "v5",
+		"ichidan": "v1",
+		"suru":    "vs-i",
+		"kuru":    "vk",
+		"zuru":    "vz",
+		"-i":      "adj-i",
+		"-na":     "adj-na",
+		"-tari":   "adj-t",
+		"-nari":   "adj-nari",
+		"-shiku":  "adj-shiku",
+		"-ku":     "adj-ku",
+	}
+
+	// Notes for the parts of speech assigned to terms.
+	wiktionaryCodeNotes = map[string]string{
+		"n":         "noun",
+		"pn":        "pronoun",
+		"adv":       "adverb",
+		"adj-pn":    "pre-noun adjectival",
+		"prt":       "particle",
+		"conj":      "conjunction",
+		"int":       "interjection",
+		"suf":       "suffix",
+		"pref":      "prefix",
+		"num":       "numeric",
+		"ctr":       "counter",
+		"exp":       "expression",
+		"aux":       "auxiliary",
+		"v5":        "godan verb",
+		"v1":        "ichidan verb",
+		"vs-i":      "suru verb",
+		"vk":        "kuru verb",
+		"vz":        "zuru verb",
+		"vt":        "transitive verb",
+		"vi":        "intransitive verb",
+		"adj-i":     "adjective (keiyoushi)",
+		"adj-na":    "adjectival noun (keiyodoshi)",
+		"adj-t":     "taru adjective",
+		"adj-nari":  "archaic form of na-adjective",
+		"adj-shiku": "shiku adjective (archaic)",
+		"adj-ku":    "ku adjective (archaic)",
+	}
+)
+
+// Returns the JMdict parts of speech of a sense, using the entry's
+// part of speech along with the conjugation described by its tags and
+// head templates.
+func wiktionaryPartsOfSpeech(entry wiktionaryEntry, sense wiktionarySense) []string {
+	var partsOfSpeech []string
+
+	if code, ok := wiktionaryPosCodes[entry.Pos]; ok {
+		partsOfSpeech = append(partsOfSpeech, code)
+	}
+
+	tags := append(append([]string{}, entry.Tags...), sense.Tags...)
+	for _, template := range entry.HeadTemplates {
+		switch template.Name {
+		case "ja-verb", "ja-verb form":
+			switch template.Args["type"] {
+			case "1":
+				tags = append(tags, "godan")
+			case "2":
+				tags = append(tags, "ichidan")
+			}
+		case "ja-verb-suru":
+			tags = append(tags, "suru")
+		case "ja-adj":
+			if infl := template.Args["infl"]; infl != "" {
+				tags = append(tags, "-"+infl)
+			}
+		}
+	}
+
+	for _, tag := range tags {
+		if code, ok := wiktionaryConjugationCodes[tag]; ok {
+			partsOfSpeech = appendStringUnique(partsOfSpeech, code)
+		}
+	}
+
+	if entry.Pos == "adj" && len(partsOfSpeech) == 0 {
+		if strings.HasSuffix(entry.Word, "い") {
+			partsOfSpeech = append(partsOfSpeech, "adj-i")
+		} else {
+			partsOfSpeech = append(partsOfSpeech, "adj-na")
+		}
+	}
+
+	if entry.Pos == "verb" && strings.HasSuffix(entry.Word, "する") && !slices.Contains(partsOfSpeech, "vs-i") {
+		partsOfSpeech = append(partsOfSpeech, "vs-i")
+	}
+
+	return partsOfSpeech
+}
+
+// Returns the expressions and readings of an entry. Kanji forms are
+// indexed as additional expressions and kana forms as readings.
+// Romanized forms are skipped, as Yomichan converts romaji input into
+// kana before searching.
+func wiktionaryHeadwords(entry wiktionaryEntry) [][2]string {
+	expressions := []string{entry.Word}
+	var readings []string
+
+	for _, form := range entry.Forms {
+		text := strings.TrimSpace(form.Form)
+		switch {
+		case text == "" || slices.Contains(form.Tags, "romanization"):
+			continue
+		case slices.Contains(form.Tags, "hiragana") || slices.Contains(form.Tags, "katakana"):
+			if isKanaOnly(text) {
+				readings = appendStringUnique(readings, text)
+			}
+		case slices.Contains(form.Tags, "kanji") || slices.Contains(form.Tags, "alternative") && !isKanaOnly(text):
+			expressions = appendStringUnique(expressions, text)
+		}
+	}
+
+	var headwords [][2]string
+	for _, expression := range expressions {
+		if isKanaOnly(expression) {
+			headwords = append(headwords, [2]string{expression, ""})
+			continue
+		}
+
+		if len(readings) == 0 {
+			headwords = append(headwords, [2]string{expression, ""})
+		}
+		for _, reading := range readings {
+			headwords = append(headwords, [2]string{expression, reading})
+		}
+	}
+
+	return headwords
+}
+
+// Wraps the parts of an example sentence which have furigana in ruby
+// elements. Ruby is given as pairs of base text and reading, in the
+// order they appear in the sentence.
+func wiktionaryRubyContent(text string, ruby [][]string) []any {
+	var contents []any
+	for _, pair := range ruby {
+		if len(pair) != 2 {
+			continue
+		}
+		index := strings.Index(text, pair[0])
+		if index == -1 {
+			continue
+		}
+		if index > 0 {
+			contents = append(contents, text[:index])
+		}
+		contents = append(contents, contentRuby(contentAttr{}, pair[1], pair[0]))
+		text = text[index+len(pair[0]):]
+	}
+	if text != "" {
+		contents = append(contents, text)
+	}
+	return contents
+}
+
+func wiktionarySenseGlossary(sense wiktionarySense) any {
+	if len(sense.Examples) == 0 && len(sense.Glosses) == 1 {
+		return sense.Glosses[0]
+	}
+
+	var items []any
+	for _, gloss := range sense.Glosses {
+		items = append(items, contentListItem(contentAttr{}, gloss))
+	}
+
+	contents := []any{contentUnorderedList(contentAttr{lang: "en", data: map[string]string{"content": "glossary"}}, items...)}
+
+	for _, example := range sense.Examples {
+		if example.Text == "" {
+			continue
+		}
+
+		exampleContents := []any{
+			contentDiv(contentAttr{lang: "ja"}, wiktionaryRubyContent(example.Text, example.Ruby)...),
+		}
+
+		translation := example.English
+		if translation == "" {
+			translation = example.Translation
+		}
+		if translation != "" {
+			exampleContents = append(exampleContents, contentDiv(contentAttr{lang: "en", fontSize: "smaller"}, translation))
+		}
+
+		contents = append(contents, contentDiv(contentAttr{marginLeft: 1, data: map[string]string{"content": "example"}}, exampleContents...))
+	}
+
+	return contentStructure(contents...)
+}
+
+// Keeps only the kana of a pronunciation, dropping accent marks and
+// numbers, e.g. "にほꜜん [2]" -> "にほん".
+func wiktionaryKana(text string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.In(r, unicode.Hiragana, unicode.Katakana) || r == 'ー' {
+			return r
+		}
+		return -1
+	}, text)
+}
+
+// Returns the downstep position of a pronunciation, which Wiktionary
+// gives either as an accent number in brackets, as a downstep marker
+// in the reading, or through the name of its accent pattern.
+func wiktionaryPitchPosition(sound wiktionarySound) (int, bool) {
+	for _, text := range []string{sound.Other, sound.Roman} {
+		if positions := findPitchPositions(text); len(positions) > 0 {
+			return positions[0], true
+		}
+	}
+
+	if index := strings.IndexRune(sound.Other, 'ꜜ'); index != -1 {
+		before := wiktionaryKana(sound.Other[:index])
+		if morae, _, _ := pitchMorae(before); len(morae) > 0 {
+			return len(morae), true
+		}
+	}
+
+	for _, tag := range sound.Tags {
+		switch strings.ToLower(tag) {
+		case "heiban":
+			return 0, true
+		case "atamadaka":
+			return 1, true
+		}
+	}
+
+	return 0, false
+}
+
+// Returns the dialect tags of a pronunciation, e.g. "Tokyo".
+func wiktionarySoundTags(sound wiktionarySound) []string {
+	var tags []string
+	for _, tag := range sound.Tags {
+		switch strings.ToLower(tag) {
+		case "heiban", "atamadaka", "nakadaka", "odaka", "kifuku":
+			continue
+		}
+		tags = append(tags, strings.ReplaceAll(tag, " ", "-"))
+	}
+	return tags
+}
+
+// Builds the pronunciation and pitch records of each headword. A sound
+// belongs to the reading given as its kana form; sounds without one,
+// or with a kana form matching no reading, are only used when the
+// entry has a single reading, as they cannot be told apart otherwise.
+func wiktionaryExtractTermMeta(entry wiktionaryEntry, headwords [][2]string) []dbMeta {
+	var readings []string
+	for _, headword := range headwords {
+		reading := headword[1]
+		if reading == "" {
+			reading = headword[0]
+		}
+		readings = appendStringUnique(readings, katakanaToHiragana(reading))
+	}
+
+	soundReadings := make([]string, len(entry.Sounds))
+	for i, sound := range entry.Sounds {
+		if kana := katakanaToHiragana(wiktionaryKana(sound.Other)); slices.Contains(readings, kana) {
+			soundReadings[i] = kana
+		} else if len(readings) == 1 {
+			soundReadings[i] = readings[0]
+		}
+	}
+
+	var metas []dbMeta
+	for _, headword := range headwords {
+		expression, reading := headword[0], headword[1]
+		if reading == "" {
+			reading = expression
+		}
+
+		var (
+			pitch          *dbMeta
+			transcriptions []phoneticTranscription
+		)
+
+		for i, sound := range entry.Sounds {
+			if soundReadings[i] != katakanaToHiragana(reading) {
+				continue
+			}
+
+			if sound.Ipa != "" {
+				transcriptions = append(transcriptions, phoneticTranscription{Ipa: sound.Ipa, Tags: wiktionarySoundTags(sound)})
+			}
+
+			position, ok := wiktionaryPitchPosition(sound)
+			if !ok {
+				continue
+			}
+			meta, ok := makePitchMeta(expression, reading, []int{position}, wiktionarySoundTags(sound))
+			if !ok {
+				continue
+			}
+
+			// every pitch of a reading goes into a single record
+			if pitch == nil {
+				pitch = &meta
+				continue
+			}
+			data := pitch.Data.(pitchAccentData)
+			for _, accent := range meta.Data.(pitchAccentData).Pitches {
+				if !slices.ContainsFunc(data.Pitches, func(a pitchAccent) bool {
+					return a.Position == accent.Position && slices.Equal(a.Tags, accent.Tags)
+				}) {
+					data.Pitches = append(data.Pitches, accent)
+				}
+			}
+			pitch.Data = data
+		}
+
+		if pitch != nil {
+			metas = append(metas, *pitch)
+		}
+		if len(transcriptions) > 0 {
+			metas = append(metas, dbMeta{expression, "ipa", phoneticData{Reading: reading, Transcriptions: transcriptions}})
+		}
+	}
+
+	return metas
+}
+
+// Builds the tag bank for the tags used by the exported terms,
+// reusing the categories of the matching JMdict tags.
+func wiktionaryTags(tagNames []string, tagNotes map[string]string) dbTagList {
+	knownTags := knownEntityTags()
+
+	var tags dbTagList
+	for _, name := range tagNames {
+		tag := dbTag{Name: name}
+		if idx := slices.IndexFunc(knownTags, func(t dbTag) bool { return t.Name == name }); idx != -1 {
+			tag = knownTags[idx]
+		}
+		if notes, ok := wiktionaryCodeNotes[name]; ok {
+			tag.Notes = notes
+		} else {
+			tag.Notes = tagNotes[name]
+		}
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+func wiktionaryExportDb(inputPath, outputPath, language, title string, stride int, pretty bool, options ExportOptions) error {
+	fp, err := os.Open(inputPath)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	var (
+		terms    dbTermList
+		termMeta dbMetaList
+		tagNames []string
+		tagNotes = make(map[string]string)
+		reader   = bufio.NewReader(fp)
+	)
+
+	for lineNumber := 1; ; lineNumber++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		if len(strings.TrimSpace(string(line))) > 0 {
+			var entry wiktionaryEntry
+			if err := json.Unmarshal(line, &entry); err != nil {
+				return fmt.Errorf("failed to parse line %d: %w", lineNumber, err)
+			}
+
+			if entry.LangCode == "" || entry.LangCode == "ja" {
+				headwords := wiktionaryHeadwords(entry)
+
+				for _, sense := range entry.Senses {
+					if len(sense.Glosses) == 0 {
+						continue
+					}
+
+					partsOfSpeech := wiktionaryPartsOfSpeech(entry, sense)
+
+					var definitionTags []string
+					definitionTags = append(definitionTags, partsOfSpeech...)
+					for _, tag := range sense.Tags {
+						if _, ok := wiktionaryConjugationCodes[tag]; ok {
+							continue
+						}
+
+						code, ok := wiktionaryMiscCodes[tag]
+						if !ok {
+							code = strings.ReplaceAll(tag, " ", "-")
+						}
+						if _, ok := tagNotes[code]; !ok {
+							tagNotes[code] = tag
+						}
+						definitionTags = appendStringUnique(definitionTags, code)
+					}
+					tagNames = appendStringUnique(tagNames, definitionTags...)
+
+					glossary := wiktionarySenseGlossary(sense)
+
+					for _, headword := range headwords {
+						term := dbTerm{
+							Expression: headword[0],
+							Reading:    headword[1],
+							Glossary:   []any{glossary},
+							Sequence:   lineNumber,
+						}
+						term.addDefinitionTags(definitionTags...)
+						term.addRules(grammarRules(partsOfSpeech)...)
+						terms = append(terms, term)
+					}
+				}
+
+				termMeta = append(termMeta, wiktionaryExtractTermMeta(entry, headwords)...)
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	if title == "" {
+		title = "Wiktionary"
+	}
+
+	recordData := map[string]dbRecordList{
+		"term":      terms.crush(),
+		"term_meta": termMeta.crush(),
+		"tag":       wiktionaryTags(tagNames, tagNotes).crush(),
+	}
+
+	index := dbIndex{
+		Title:       title,
+		Revision:    "wiktionary",
+		Sequenced:   true,
+		Attribution: "Wiktionary content is available under the Creative Commons Attribution-ShareAlike License. Extracted with Wiktextract (kaikki.org).",
+	}
+
+	return writeDb(
+		outputPath,
+		index,
+		recordData,
+		stride,
+		pretty,
+	)
+}
@@ -0,0 +1,64 @@
+package yomichan
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestWiktionaryExtractTermMeta(t *testing.T) {
+	tests := []struct {
+		name      string
+		headwords [][2]string
+		sounds    []wiktionarySound
+		want      []dbMeta
+	}{
+		{
+			name:      "two readings",
+			headwords: [][2]string{{"日本", "にほん"}, {"日本", "にっぽん"}},
+			sounds: []wiktionarySound{
+				{Other: "にほꜜん", Tags: []string{"Tokyo"}},
+				{Other: "にっぽꜜん"},
+				{Other: "ニホン", Tags: []string{"heiban"}},
+				{Ipa: "[ɲihoɴ]", Other: "にほん"},
+				{Ipa: "[ɲippoɴ]", Other: "にっぽん"},
+				{Ipa: "[nihon]"},
+			},
+			want: []dbMeta{
+				{"日本", "pitch", pitchAccentData{Reading: "にほん", Pitches: []pitchAccent{{Position: 2, Tags: []string{"Tokyo"}}, {Position: 0}}}},
+				{"日本", "ipa", phoneticData{Reading: "にほん", Transcriptions: []phoneticTranscription{{Ipa: "[ɲihoɴ]"}}}},
+				{"日本", "pitch", pitchAccentData{Reading: "にっぽん", Pitches: []pitchAccent{{Position: 3}}}},
+				{"日本", "ipa", phoneticData{Reading: "にっぽん", Transcriptions: []phoneticTranscription{{Ipa: "[ɲippoɴ]"}}}},
+			},
+		},
+		{
+			name:      "single reading",
+			headwords: [][2]string{{"猫", "ねこ"}, {"貓", "ねこ"}},
+			sounds: []wiktionarySound{
+				{Other: "ねꜜこ"},
+				{Tags: []string{"atamadaka"}},
+				{Ipa: "[neko]"},
+			},
+			want: []dbMeta{
+				{"猫", "pitch", pitchAccentData{Reading: "ねこ", Pitches: []pitchAccent{{Position: 1}}}},
+				{"猫", "ipa", phoneticData{Reading: "ねこ", Transcriptions: []phoneticTranscription{{Ipa: "[neko]"}}}},
+				{"貓", "pitch", pitchAccentData{Reading: "ねこ", Pitches: []pitchAccent{{Position: 1}}}},
+				{"貓", "ipa", phoneticData{Reading: "ねこ", Transcriptions: []phoneticTranscription{{Ipa: "[neko]"}}}},
+			},
+		},
+		{
+			name:      "kana headword",
+			headwords: [][2]string{{"すし", ""}},
+			sounds:    []wiktionarySound{{Ipa: "[sɯᵝɕi]", Tags: []string{"Tokyo"}}},
+			want: []dbMeta{
+				{"すし", "ipa", phoneticData{Reading: "すし", Transcriptions: []phoneticTranscription{{Ipa: "[sɯᵝɕi]", Tags: []string{"Tokyo"}}}}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		entry := wiktionaryEntry{Sounds: test.sounds}
+		if got := wiktionaryExtractTermMeta(entry, test.headwords); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: wiktionaryExtractTermMeta() = %+v, want %+v", test.name, got, test.want)
+		}
+	}
+}
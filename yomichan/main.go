@@ -6,12 +6,14 @@ import (
 	"log"
 	"os"
 	"path"
+	"strings"
 
 	yomichan "foosoft.net/projects/yomichan-import"
 )
 
 func usage() {
 	fmt.Fprintf(os.Stderr, "Usage: %s [options] input-path output-path\n", path.Base(os.Args[0]))
+	fmt.Fprintf(os.Stderr, "       %s list-subbooks input-path\n", path.Base(os.Args[0]))
 	fmt.Fprint(os.Stderr, "https://foosoft.net/projects/yomichan-import/\n\n")
 	fmt.Fprint(os.Stderr, "Parameters:\n")
 	flag.PrintDefaults()
@@ -19,11 +21,30 @@ func usage() {
 
 func main() {
 	var (
-		format   = flag.String("format", yomichan.DefaultFormat, "dictionary format [edict|enamdict|epwing|kanjidic|rikai]")
+		format   = flag.String("format", yomichan.DefaultFormat, "dictionary format [anki|cedict|corpusfreq|dsl|edict|edict2|enamdict|epwing|ipa|kanjidic|kanjivg|mdict|pitch|rikai|stardict|tatoeba|tsv|wiktionary]")
 		language = flag.String("language", yomichan.DefaultLanguage, "dictionary language (if supported)")
 		title    = flag.String("title", yomichan.DefaultTitle, "dictionary title")
 		stride   = flag.Int("stride", yomichan.DefaultStride, "dictionary bank stride")
 		pretty   = flag.Bool("pretty", yomichan.DefaultPretty, "output prettified dictionary JSON")
+
+		subbooks        = flag.String("subbooks", "", "comma-separated EPWING subbook titles or indices to convert")
+		splitSubbooks   = flag.Bool("split-subbooks", false, "write each EPWING subbook to its own archive")
+		skipUnsupported = flag.Bool("skip-unsupported", false, "skip unsupported EPWING subbooks instead of failing")
+		mergeSubbooks   = flag.Bool("merge-subbooks", false, "group EPWING terms sharing a headword across subbooks")
+		fields          = flag.String("fields", "", "comma-separated role=field mappings for Anki notes or TSV/CSV columns")
+		delimiter       = flag.String("delimiter", "", "TSV/CSV column delimiter (tab, comma, semicolon, pipe or a character)")
+		header          = flag.Bool("header", false, "TSV/CSV files begin with a header row")
+		separator       = flag.String("separator", "", "separator between multiple glosses in a TSV/CSV column")
+		frequencyMode   = flag.String("frequency-mode", "", "frequency values are ranks or occurrence counts [rank|occurrence]")
+		lexicon         = flag.String("lexicon", "", "JMdict file used to count headwords in a text corpus or to add readings to Tatoeba examples")
+		minCount        = flag.Int("min-count", 1, "minimum number of occurrences in a text corpus")
+		kanjiVariants   = flag.Bool("kanji-variants", false, "add kanji entries for variant characters without meanings")
+		kradfiles       = flag.String("kradfile", "", "comma-separated KRADFILE paths with kanji components")
+		radkfile        = flag.String("radkfile", "", "RADKFILE path with kanji components")
+		kanjiVG         = flag.String("kanjivg", "", "KanjiVG XML file or SVG directory with stroke order diagrams")
+		kanjiVGColors   = flag.Bool("kanjivg-colors", false, "color each stroke of the KanjiVG stroke order diagrams")
+		kanjiVGTerms    = flag.Bool("kanjivg-terms", false, "add the KanjiVG stroke order diagrams to KANJIDIC as term entries")
+		kanjiReadings   = flag.String("kanji-readings", "", "comma-separated extra KANJIDIC readings [all|pinyin|korean_r|korean_h|vietnam]")
 	)
 
 	flag.Usage = usage
@@ -34,7 +55,71 @@ func main() {
 		os.Exit(2)
 	}
 
-	if err := yomichan.ExportDb(flag.Arg(0), flag.Arg(1), *format, *language, *title, *stride, *pretty); err != nil {
+	if flag.Arg(0) == "list-subbooks" {
+		listSubbooks(flag.Arg(1))
+		return
+	}
+
+	options := yomichan.ExportOptions{
+		Subbooks:        splitList(*subbooks),
+		SplitSubbooks:   *splitSubbooks,
+		SkipUnsupported: *skipUnsupported,
+		MergeSubbooks:   *mergeSubbooks,
+		Fields:          splitFields(*fields),
+		Delimiter:       *delimiter,
+		Header:          *header,
+		Separator:       *separator,
+		FrequencyMode:   *frequencyMode,
+		Lexicon:         *lexicon,
+		MinCount:        *minCount,
+		KanjiReadings:   splitList(*kanjiReadings),
+		Kradfiles:       splitList(*kradfiles),
+		Radkfile:        *radkfile,
+		KanjiVariants:   *kanjiVariants,
+		KanjiVG:         *kanjiVG,
+		KanjiVGColors:   *kanjiVGColors,
+		KanjiVGTerms:    *kanjiVGTerms,
+	}
+
+	if err := yomichan.ExportDbWithOptions(flag.Arg(0), flag.Arg(1), *format, *language, *title, *stride, *pretty, options); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func listSubbooks(inputPath string) {
+	subbooks, err := yomichan.ListEpwingSubbooks(inputPath)
+	if err != nil {
 		log.Fatal(err)
 	}
+
+	for i, subbook := range subbooks {
+		status := "supported"
+		if !subbook.Supported {
+			status = "unsupported"
+		}
+		fmt.Printf("%d\t%s\t%d entries\t%s\n", i+1, subbook.Title, subbook.Entries, status)
+	}
+}
+
+func splitList(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			values = append(values, part)
+		}
+	}
+	return values
+}
+
+func splitFields(value string) map[string][]string {
+	fields := make(map[string][]string)
+	for _, part := range splitList(value) {
+		role, field, ok := strings.Cut(part, "=")
+		if !ok {
+			log.Fatalf("invalid field mapping: %s", part)
+		}
+		role = strings.TrimSpace(role)
+		fields[role] = append(fields[role], strings.TrimSpace(field))
+	}
+	return fields
 }